@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"test/models"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAPIKeys отдаёт ключи по сырому значению, "broken" имитирует ошибку БД.
+type fakeAPIKeys map[string]models.APIKey
+
+func (f fakeAPIKeys) LookupAPIKey(ctx context.Context, rawKey string) (models.APIKey, error) {
+	if rawKey == "broken" {
+		return models.APIKey{}, errors.New("connection refused")
+	}
+	key, ok := f[rawKey]
+	if !ok {
+		return models.APIKey{}, errors.New("api key not found")
+	}
+	return key, nil
+}
+
+var testAPIKeys = fakeAPIKeys{
+	"read":  {ID: 1, OrganizationID: "acme", Scopes: models.StringList{models.ScopeRead}},
+	"write": {ID: 2, OrganizationID: "acme", Scopes: models.StringList{models.ScopeWrite}},
+	"admin": {ID: 3, OrganizationID: "acme", Scopes: models.StringList{models.ScopeAdmin}},
+}
+
+func TestMiddlewareAPIKeyScopes(t *testing.T) {
+	app := newTestApp(New(nil, testAPIKeys, nil, []string{"/graphql"}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		wantStatus int
+	}{
+		{name: "read key reads", method: "GET", path: "/api/v1/subscriptions", key: "read", wantStatus: 200},
+		{name: "read key on head", method: "HEAD", path: "/api/v1/subscriptions", key: "read", wantStatus: 200},
+		{name: "read key writes", method: "POST", path: "/api/v1/subscriptions", key: "read", wantStatus: 403},
+		{name: "read key deletes", method: "DELETE", path: "/api/v1/subscriptions/1", key: "read", wantStatus: 403},
+		{name: "write key writes", method: "PUT", path: "/api/v1/subscriptions/1", key: "write", wantStatus: 200},
+		{name: "write key reads", method: "GET", path: "/api/v1/subscriptions", key: "write", wantStatus: 403},
+		{name: "admin key reads", method: "GET", path: "/api/v1/subscriptions", key: "admin", wantStatus: 200},
+		{name: "admin key writes", method: "POST", path: "/api/v1/subscriptions", key: "admin", wantStatus: 200},
+		{name: "read key posts to read route", method: "POST", path: "/graphql", key: "read", wantStatus: 200},
+		{name: "write key on read route", method: "POST", path: "/graphql", key: "write", wantStatus: 403},
+		{name: "unknown key", method: "GET", path: "/api/v1/subscriptions", key: "unknown", wantStatus: 401},
+		{name: "lookup error", method: "GET", path: "/api/v1/subscriptions", key: "broken", wantStatus: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := send(t, app, testRequest{tt.method, tt.path, map[string]string{APIKeyHeader: tt.key}})
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestClaimsFromAPIKey(t *testing.T) {
+	claims := claimsFromAPIKey(testAPIKeys["admin"])
+	if claims.APIKeyID != 3 || claims.OrganizationID != "acme" || !claims.HasRole(RoleAdmin) {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	claims = claimsFromAPIKey(testAPIKeys["read"])
+	if claims.HasRole(RoleAdmin) || claims.Subject != "" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestClaimsAllowsScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *Claims
+		scope  string
+		want   bool
+	}{
+		{name: "token", claims: &Claims{Scope: "read"}, scope: models.ScopeWrite, want: true},
+		{name: "read key reads", claims: claimsFromAPIKey(testAPIKeys["read"]), scope: models.ScopeRead, want: true},
+		{name: "read key writes", claims: claimsFromAPIKey(testAPIKeys["read"]), scope: models.ScopeWrite, want: false},
+		{name: "admin key writes", claims: claimsFromAPIKey(testAPIKeys["admin"]), scope: models.ScopeWrite, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.AllowsScope(tt.scope); got != tt.want {
+				t.Errorf("AllowsScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptorAPIKeyScopes(t *testing.T) {
+	interceptor := UnaryServerInterceptor(nil, testAPIKeys,
+		[]string{"/svc/Get"}, []string{"/grpc.health.v1.Health/*"})
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		if _, ok := ClaimsFromContext(ctx); !ok {
+			return "anonymous", nil
+		}
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		key      string
+		wantCode codes.Code
+	}{
+		{name: "read key on read method", method: "/svc/Get", key: "read", wantCode: codes.OK},
+		{name: "read key on write method", method: "/svc/Create", key: "read", wantCode: codes.PermissionDenied},
+		{name: "write key on write method", method: "/svc/Create", key: "write", wantCode: codes.OK},
+		{name: "admin key on write method", method: "/svc/Create", key: "admin", wantCode: codes.OK},
+		{name: "unknown key", method: "/svc/Get", key: "unknown", wantCode: codes.Unauthenticated},
+		{name: "lookup error", method: "/svc/Get", key: "broken", wantCode: codes.Internal},
+		{name: "no credentials", method: "/svc/Get", wantCode: codes.Unauthenticated},
+		{name: "public method", method: "/grpc.health.v1.Health/Check", wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", tt.key))
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %s, want %s (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
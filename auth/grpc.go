@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"test/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type grpcClaimsKey struct{}
+
+// UnaryServerInterceptor — аналог New для gRPC. Принимает API-ключ в метаданных x-api-key
+// или authorization: Bearer <token>. Методы из readMethods требуют у API-ключа scope read, остальные — write.
+// publicMethods задаются как publicRoutes в New, например "/grpc.health.v1.Health/*".
+func UnaryServerInterceptor(verifier *Verifier, apiKeys APIKeyLookup, readMethods, publicMethods []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if matchRoute(info.FullMethod, publicMethods) {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		var claims *Claims
+
+		if rawKey := firstValue(md, strings.ToLower(APIKeyHeader)); rawKey != "" {
+			key, err := apiKeys.LookupAPIKey(ctx, rawKey)
+			if err != nil {
+				if err.Error() == "api key not found" {
+					return nil, status.Error(codes.Unauthenticated, "invalid api key")
+				}
+				slog.ErrorContext(ctx, "failed to check api key", "error", err)
+				return nil, status.Error(codes.Internal, "failed to check api key")
+			}
+
+			claims = claimsFromAPIKey(key)
+			scope := models.ScopeWrite
+			for _, method := range readMethods {
+				if method == info.FullMethod {
+					scope = models.ScopeRead
+				}
+			}
+			if !key.HasScope(scope) {
+				return nil, status.Error(codes.PermissionDenied, "api key lacks "+scope+" scope")
+			}
+		} else {
+			token, ok := strings.CutPrefix(firstValue(md, "authorization"), "Bearer ")
+			if !ok || token == "" {
+				return nil, status.Error(codes.Unauthenticated, "missing bearer token or api key")
+			}
+
+			if verifier == nil {
+				return nil, status.Error(codes.Unauthenticated, "bearer tokens are not accepted")
+			}
+
+			var err error
+			claims, err = verifier.Verify(token)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "invalid token: "+err.Error())
+			}
+		}
+
+		return handler(context.WithValue(ctx, grpcClaimsKey{}, claims), req)
+	}
+}
+
+// ClaimsFromContext возвращает claims, сохранённые UnaryServerInterceptor.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(grpcClaimsKey{}).(*Claims)
+	return claims, ok
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"test/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const RoleAdmin = "admin"
+
+// Claims — данные вызывающего, извлечённые из токена.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+
+	// OrganizationID — организация вызывающего, без claim используется models.DefaultOrganization
+	OrganizationID string `json:"org_id,omitempty"`
+
+	// APIKeyID заполняется, если вызывающий аутентифицирован API-ключом
+	APIKeyID int `json:"-"`
+}
+
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// AllowsScope сообщает, разрешает ли API-ключ вызывающего scope. Scope токенов не проверяются,
+// поэтому для них всегда true.
+func (c *Claims) AllowsScope(scope string) bool {
+	if c.APIKeyID == 0 {
+		return true
+	}
+	for _, s := range c.Scopes() {
+		if s == scope || s == models.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type Config struct {
+	Secret   string
+	JWKSFile string
+	Issuer   string
+	Audience string
+}
+
+// Verifier проверяет HS256-токены по общему секрету и RS256-токены по ключам из JWKS.
+type Verifier struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	v := &Verifier{}
+	var methods []string
+
+	if cfg.Secret != "" {
+		v.secret = []byte(cfg.Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("jwt_secret or jwt_jwks_file must be set")
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
+}
+
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := v.parser.ParseWithClaims(tokenString, claims, v.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// Токен без kid допустим, если в JWKS ровно один ключ
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
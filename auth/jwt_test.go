@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// writeJWKS сохраняет открытые ключи в JWKS-файл и возвращает путь к нему.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	t.Helper()
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":    "550e8400-e29b-41d4-a716-446655440000",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iss":    "issuer",
+		"aud":    "subscriptions",
+		"roles":  []string{RoleAdmin},
+		"scope":  "read write",
+		"org_id": "acme",
+	}
+}
+
+func withClaim(name string, value interface{}) jwt.MapClaims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerifierHS256(t *testing.T) {
+	verifier, err := NewVerifier(Config{Secret: testSecret, Issuer: "issuer", Audience: "subscriptions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: signHS256(t, testSecret, validClaims())},
+		{name: "wrong secret", token: signHS256(t, "other", validClaims()), wantErr: true},
+		{name: "expired", token: signHS256(t, testSecret, withClaim("exp", time.Now().Add(-time.Minute).Unix())), wantErr: true},
+		{name: "no expiration", token: signHS256(t, testSecret, withClaim("exp", nil)), wantErr: true},
+		{name: "wrong issuer", token: signHS256(t, testSecret, withClaim("iss", "other")), wantErr: true},
+		{name: "wrong audience", token: signHS256(t, testSecret, withClaim("aud", "other")), wantErr: true},
+		{name: "rs256 without jwks", token: signRS256(t, generateRSAKey(t), "", validClaims()), wantErr: true},
+		{name: "not a token", token: "abc.def.ghi", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Verify() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if claims.Subject != "550e8400-e29b-41d4-a716-446655440000" || claims.OrganizationID != "acme" {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+			if !claims.HasRole(RoleAdmin) {
+				t.Errorf("Verify() roles = %v, want admin", claims.Roles)
+			}
+			if scopes := claims.Scopes(); len(scopes) != 2 || scopes[0] != "read" || scopes[1] != "write" {
+				t.Errorf("Scopes() = %v", scopes)
+			}
+		})
+	}
+}
+
+func TestVerifierNoneAlgorithm(t *testing.T) {
+	verifier, err := NewVerifier(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifier.Verify(token); err == nil {
+		t.Fatal("Verify() accepted an unsigned token")
+	}
+}
+
+func TestVerifierRS256(t *testing.T) {
+	first, second, unknown := generateRSAKey(t), generateRSAKey(t), generateRSAKey(t)
+
+	multiple, err := NewVerifier(Config{JWKSFile: writeJWKS(t, map[string]*rsa.PrivateKey{"first": first, "second": second})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := NewVerifier(Config{JWKSFile: writeJWKS(t, map[string]*rsa.PrivateKey{"only": first})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+		wantErr  bool
+	}{
+		{name: "first kid", verifier: multiple, token: signRS256(t, first, "first", validClaims())},
+		{name: "second kid", verifier: multiple, token: signRS256(t, second, "second", validClaims())},
+		{name: "kid of another key", verifier: multiple, token: signRS256(t, first, "second", validClaims()), wantErr: true},
+		{name: "unknown kid", verifier: multiple, token: signRS256(t, unknown, "unknown", validClaims()), wantErr: true},
+		{name: "no kid with several keys", verifier: multiple, token: signRS256(t, first, "", validClaims()), wantErr: true},
+		{name: "no kid with one key", verifier: single, token: signRS256(t, first, "", validClaims())},
+		{name: "hs256 without secret", verifier: single, token: signHS256(t, testSecret, validClaims()), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVerifierConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "secret", cfg: Config{Secret: testSecret}},
+		{name: "nothing configured", cfg: Config{}, wantErr: true},
+		{name: "missing jwks file", cfg: Config{JWKSFile: filepath.Join(dir, "missing.json")}, wantErr: true},
+		{name: "invalid json", cfg: Config{JWKSFile: write("invalid.json", "{")}, wantErr: true},
+		{name: "no rsa keys", cfg: Config{JWKSFile: write("ec.json", `{"keys":[{"kty":"EC","kid":"ec"}]}`)}, wantErr: true},
+		{name: "encryption key only", cfg: Config{JWKSFile: write("enc.json", `{"keys":[{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}]}`)}, wantErr: true},
+		{name: "invalid modulus", cfg: Config{JWKSFile: write("modulus.json", `{"keys":[{"kty":"RSA","kid":"bad","n":"***","e":"AQAB"}]}`)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewVerifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"test/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	claimsKey    = "auth_claims"
+	APIKeyHeader = "X-API-Key"
+)
+
+type APIKeyLookup interface {
+	LookupAPIKey(ctx context.Context, rawKey string) (models.APIKey, error)
+}
+
+// New возвращает middleware, которое требует валидный Bearer-токен или API-ключ в X-API-Key
+// на всех маршрутах, кроме publicRoutes. Шаблон с * на конце совпадает по префиксу, остальные — точно.
+// На readRoutes API-ключу достаточно scope read при любом методе: право на запись проверяет обработчик
+// через Claims.AllowsScope. verifier может быть nil, если принимаются только API-ключи.
+func New(verifier *Verifier, apiKeys APIKeyLookup, publicRoutes, readRoutes []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if matchRoute(c.Path(), publicRoutes) {
+			return c.Next()
+		}
+
+		var claims *Claims
+
+		if rawKey := c.Get(APIKeyHeader); rawKey != "" {
+			key, err := apiKeys.LookupAPIKey(c.UserContext(), rawKey)
+			if err != nil {
+				if err.Error() == "api key not found" {
+					return unauthorized(c, "invalid api key")
+				}
+				slog.ErrorContext(c.UserContext(), "failed to check api key", "error", err)
+				return c.Status(500).JSON(models.ErrorResponse{
+					Status:  false,
+					Message: "failed to check api key",
+				})
+			}
+
+			claims = claimsFromAPIKey(key)
+			scope := requiredScope(c.Method())
+			if matchRoute(c.Path(), readRoutes) {
+				scope = models.ScopeRead
+			}
+			if !key.HasScope(scope) {
+				return c.Status(403).JSON(models.ErrorResponse{
+					Status:  false,
+					Message: "api key lacks " + scope + " scope",
+				})
+			}
+		} else {
+			header := c.Get(fiber.HeaderAuthorization)
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || token == "" {
+				return unauthorized(c, "missing bearer token or api key")
+			}
+
+			if verifier == nil {
+				return unauthorized(c, "bearer tokens are not accepted")
+			}
+
+			var err error
+			claims, err = verifier.Verify(token)
+			if err != nil {
+				return unauthorized(c, "invalid token: "+err.Error())
+			}
+		}
+
+		c.Locals(claimsKey, claims)
+		return c.Next()
+	}
+}
+
+func claimsFromAPIKey(key models.APIKey) *Claims {
+	claims := &Claims{
+		APIKeyID:       key.ID,
+		Scope:          strings.Join(key.Scopes, " "),
+		OrganizationID: key.OrganizationID,
+	}
+
+	if key.UserID != nil {
+		claims.Subject = key.UserID.String()
+	}
+
+	if key.HasScope(models.ScopeAdmin) {
+		claims.Roles = []string{RoleAdmin}
+	}
+
+	return claims
+}
+
+func requiredScope(method string) string {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return models.ScopeRead
+	}
+	return models.ScopeWrite
+}
+
+// ClaimsFromCtx возвращает claims, сохранённые middleware для текущего запроса.
+func ClaimsFromCtx(c *fiber.Ctx) (*Claims, bool) {
+	claims, ok := c.Locals(claimsKey).(*Claims)
+	return claims, ok
+}
+
+func matchRoute(path string, routes []string) bool {
+	for _, route := range routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == route {
+			return true
+		}
+	}
+	return false
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="api"`)
+	return c.Status(401).JSON(models.ErrorResponse{
+		Status:  false,
+		Message: message,
+	})
+}
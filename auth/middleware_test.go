@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp собирает приложение с middleware и обработчиком, который возвращает claims запроса.
+func newTestApp(middleware fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Use(middleware)
+	app.All("/*", func(c *fiber.Ctx) error {
+		claims, ok := ClaimsFromCtx(c)
+		if !ok {
+			return c.SendString("anonymous")
+		}
+		return c.JSON(claims)
+	})
+	return app
+}
+
+type testRequest struct {
+	method  string
+	path    string
+	headers map[string]string
+}
+
+func send(t *testing.T, app *fiber.App, r testRequest) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(r.method, r.path, nil)
+	for name, value := range r.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body json.RawMessage
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, string(body)
+}
+
+func TestMiddlewareBearer(t *testing.T) {
+	verifier, err := NewVerifier(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp(New(verifier, nil, []string{"/healthz", "/swagger/*"}, nil))
+
+	bearer := func(token string) map[string]string {
+		return map[string]string{fiber.HeaderAuthorization: "Bearer " + token}
+	}
+
+	tests := []struct {
+		name       string
+		request    testRequest
+		wantStatus int
+	}{
+		{name: "valid token", request: testRequest{"GET", "/api/v1/subscriptions", bearer(signHS256(t, testSecret, validClaims()))}, wantStatus: 200},
+		{name: "valid token on write", request: testRequest{"POST", "/api/v1/subscriptions", bearer(signHS256(t, testSecret, validClaims()))}, wantStatus: 200},
+		{name: "no credentials", request: testRequest{"GET", "/api/v1/subscriptions", nil}, wantStatus: 401},
+		{name: "not bearer", request: testRequest{"GET", "/api/v1/subscriptions", map[string]string{fiber.HeaderAuthorization: "Basic dXNlcjpwYXNz"}}, wantStatus: 401},
+		{name: "empty bearer", request: testRequest{"GET", "/api/v1/subscriptions", bearer("")}, wantStatus: 401},
+		{name: "invalid token", request: testRequest{"GET", "/api/v1/subscriptions", bearer(signHS256(t, "other", validClaims()))}, wantStatus: 401},
+		{name: "public route", request: testRequest{"GET", "/healthz", nil}, wantStatus: 200},
+		{name: "public prefix", request: testRequest{"GET", "/swagger/index.html", nil}, wantStatus: 200},
+		{name: "exact route is not a prefix", request: testRequest{"GET", "/healthz/details", nil}, wantStatus: 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := send(t, app, tt.request)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWithoutVerifier(t *testing.T) {
+	app := newTestApp(New(nil, nil, nil, nil))
+
+	status, _ := send(t, app, testRequest{"GET", "/api/v1/subscriptions", map[string]string{
+		fiber.HeaderAuthorization: "Bearer " + signHS256(t, testSecret, validClaims()),
+	}})
+	if status != 401 {
+		t.Errorf("status = %d, want 401", status)
+	}
+}
+
+func TestMiddlewareStoresClaims(t *testing.T) {
+	verifier, err := NewVerifier(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp(New(verifier, nil, nil, nil))
+
+	status, body := send(t, app, testRequest{"GET", "/api/v1/subscriptions", map[string]string{
+		fiber.HeaderAuthorization: "Bearer " + signHS256(t, testSecret, validClaims()),
+	}})
+	if status != 200 {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal([]byte(body), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.OrganizationID != "acme" || claims.Subject != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"errors"
+	"test/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ScopeFromCtx определяет, к чьим подпискам у вызывающего есть доступ.
+// Организация берётся из claim org_id (или API-ключа), по умолчанию — models.DefaultOrganization.
+// Администратор, API-ключ без владельца и запросы без claims (аутентификация отключена)
+// видят всех пользователей организации, остальные — только подписки с user_id из claim sub.
+func ScopeFromCtx(c *fiber.Ctx) (models.Scope, error) {
+	claims, _ := ClaimsFromCtx(c)
+	return ScopeFromClaims(claims)
+}
+
+// ScopeFromClaims — то же, что ScopeFromCtx, для claims, полученных не из HTTP-запроса.
+// nil claims означает, что аутентификация отключена.
+func ScopeFromClaims(claims *Claims) (models.Scope, error) {
+	scope := models.Scope{OrganizationID: models.DefaultOrganization}
+
+	if claims == nil {
+		return scope, nil
+	}
+
+	if claims.OrganizationID != "" {
+		if claims.OrganizationID == models.SystemOrganization {
+			return models.Scope{}, errors.New("invalid organization")
+		}
+		scope.OrganizationID = claims.OrganizationID
+	}
+
+	if claims.HasRole(RoleAdmin) || (claims.APIKeyID != 0 && claims.Subject == "") {
+		return scope, nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return models.Scope{}, errors.New("token subject must be a user UUID")
+	}
+
+	scope.UserID = &userID
+	return scope, nil
+}
+
+// RequireAdmin пропускает только администраторов.
+func RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if claims, ok := ClaimsFromCtx(c); ok && !claims.HasRole(RoleAdmin) {
+			return c.Status(403).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "admin role required",
+			})
+		}
+		return c.Next()
+	}
+}
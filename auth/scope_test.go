@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"test/models"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestScopeFromClaims(t *testing.T) {
+	userID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name     string
+		claims   *Claims
+		wantOrg  string
+		wantUser *uuid.UUID
+		wantErr  bool
+	}{
+		{name: "auth disabled", claims: nil, wantOrg: models.DefaultOrganization},
+		{name: "user", claims: &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID.String()}}, wantOrg: models.DefaultOrganization, wantUser: &userID},
+		{name: "user in organization", claims: &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID.String()}, OrganizationID: "acme"}, wantOrg: "acme", wantUser: &userID},
+		{name: "admin sees all users", claims: &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID.String()}, Roles: []string{RoleAdmin}, OrganizationID: "acme"}, wantOrg: "acme"},
+		{name: "admin without subject", claims: &Claims{Roles: []string{RoleAdmin}}, wantOrg: models.DefaultOrganization},
+		{name: "api key without owner", claims: &Claims{APIKeyID: 1, OrganizationID: "acme"}, wantOrg: "acme"},
+		{name: "api key with owner", claims: &Claims{APIKeyID: 1, RegisteredClaims: jwt.RegisteredClaims{Subject: userID.String()}}, wantOrg: models.DefaultOrganization, wantUser: &userID},
+		{name: "subject is not a uuid", claims: &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}, wantErr: true},
+		{name: "no subject", claims: &Claims{}, wantErr: true},
+		{name: "system organization", claims: &Claims{Roles: []string{RoleAdmin}, OrganizationID: models.SystemOrganization}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := ScopeFromClaims(tt.claims)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ScopeFromClaims() = %+v, want error", scope)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ScopeFromClaims() error = %v", err)
+			}
+
+			if scope.OrganizationID != tt.wantOrg {
+				t.Errorf("OrganizationID = %q, want %q", scope.OrganizationID, tt.wantOrg)
+			}
+			switch {
+			case tt.wantUser == nil && scope.UserID != nil:
+				t.Errorf("UserID = %s, want all users", scope.UserID)
+			case tt.wantUser != nil && (scope.UserID == nil || *scope.UserID != *tt.wantUser):
+				t.Errorf("UserID = %v, want %s", scope.UserID, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *Claims
+		wantStatus int
+	}{
+		{name: "admin", claims: &Claims{Roles: []string{RoleAdmin}}, wantStatus: 200},
+		{name: "user", claims: &Claims{Roles: []string{"user"}}, wantStatus: 403},
+		{name: "auth disabled", claims: nil, wantStatus: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				if tt.claims != nil {
+					c.Locals(claimsKey, tt.claims)
+				}
+				return c.Next()
+			})
+			app.Use(RequireAdmin())
+			app.Get("/api/v1/admin", func(c *fiber.Ctx) error {
+				return c.SendStatus(200)
+			})
+
+			status, _ := send(t, app, testRequest{"GET", "/api/v1/admin", nil})
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
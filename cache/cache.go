@@ -0,0 +1,22 @@
+// Package cache — кеш ответов тяжёлых запросов на чтение.
+package cache
+
+import "context"
+
+// Cache хранит сериализованные значения по строковому ключу.
+// Реализации должны быть безопасны для конкурентного использования.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte)
+	// DeletePrefix удаляет все ключи с префиксом prefix; пустой prefix очищает кеш.
+	DeletePrefix(ctx context.Context, prefix string)
+}
+
+// Noop ничего не хранит; используется, когда кеш отключён.
+type Noop struct{}
+
+func (Noop) Get(context.Context, string) ([]byte, bool) { return nil, false }
+
+func (Noop) Set(context.Context, string, []byte) {}
+
+func (Noop) DeletePrefix(context.Context, string) {}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	type step struct {
+		op    string // set, get, delete
+		key   string
+		value string
+		// want — ожидаемое значение get, пустая строка — промах
+		want string
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		steps    []step
+	}{
+		{
+			name:     "get after set",
+			capacity: 2,
+			steps: []step{
+				{op: "set", key: "a", value: "1"},
+				{op: "get", key: "a", want: "1"},
+				{op: "get", key: "b"},
+			},
+		},
+		{
+			name:     "overwrite",
+			capacity: 2,
+			steps: []step{
+				{op: "set", key: "a", value: "1"},
+				{op: "set", key: "a", value: "2"},
+				{op: "get", key: "a", want: "2"},
+			},
+		},
+		{
+			name:     "evicts least recently used",
+			capacity: 2,
+			steps: []step{
+				{op: "set", key: "a", value: "1"},
+				{op: "set", key: "b", value: "2"},
+				{op: "get", key: "a", want: "1"},
+				{op: "set", key: "c", value: "3"},
+				{op: "get", key: "b"},
+				{op: "get", key: "a", want: "1"},
+				{op: "get", key: "c", want: "3"},
+			},
+		},
+		{
+			name:     "overwrite refreshes recency",
+			capacity: 2,
+			steps: []step{
+				{op: "set", key: "a", value: "1"},
+				{op: "set", key: "b", value: "2"},
+				{op: "set", key: "a", value: "3"},
+				{op: "set", key: "c", value: "4"},
+				{op: "get", key: "b"},
+				{op: "get", key: "a", want: "3"},
+			},
+		},
+		{
+			name:     "delete prefix",
+			capacity: 10,
+			steps: []step{
+				{op: "set", key: "total:acme:1", value: "1"},
+				{op: "set", key: "list:acme:1", value: "2"},
+				{op: "set", key: "total:other:1", value: "3"},
+				{op: "delete", key: "total:acme:"},
+				{op: "get", key: "total:acme:1"},
+				{op: "get", key: "list:acme:1", want: "2"},
+				{op: "get", key: "total:other:1", want: "3"},
+			},
+		},
+		{
+			name:     "empty prefix clears everything",
+			capacity: 10,
+			steps: []step{
+				{op: "set", key: "a", value: "1"},
+				{op: "set", key: "b", value: "2"},
+				{op: "delete", key: ""},
+				{op: "get", key: "a"},
+				{op: "get", key: "b"},
+				{op: "set", key: "c", value: "3"},
+				{op: "get", key: "c", want: "3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lru := NewLRU(tt.capacity, time.Minute)
+
+			for i, s := range tt.steps {
+				switch s.op {
+				case "set":
+					lru.Set(ctx, s.key, []byte(s.value))
+				case "delete":
+					lru.DeletePrefix(ctx, s.key)
+				case "get":
+					value, ok := lru.Get(ctx, s.key)
+					if ok != (s.want != "") || string(value) != s.want {
+						t.Errorf("step %d: Get(%q) = %q, %v, want %q", i, s.key, value, ok, s.want)
+					}
+				}
+			}
+
+			if lru.order.Len() != len(lru.items) || len(lru.items) > tt.capacity {
+				t.Errorf("list has %d entries, map %d, capacity %d", lru.order.Len(), len(lru.items), tt.capacity)
+			}
+		})
+	}
+}
+
+func TestLRUExpiration(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(10, time.Minute)
+
+	lru.Set(ctx, "a", []byte("1"))
+	lru.items["a"].Value.(*lruEntry).expiresAt = time.Now().Add(-time.Second)
+
+	if _, ok := lru.Get(ctx, "a"); ok {
+		t.Fatal("expired entry was returned")
+	}
+	if _, ok := lru.items["a"]; ok {
+		t.Error("expired entry was not removed")
+	}
+
+	lru.Set(ctx, "a", []byte("2"))
+	if value, ok := lru.Get(ctx, "a"); !ok || string(value) != "2" {
+		t.Errorf("Get() after re-set = %q, %v", value, ok)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"test/config"
+	"test/db"
+)
+
+// runAggregates выполняет подкоманду aggregates: check или rebuild.
+// check завершается ошибкой, если агрегат расходится с подписками.
+func runAggregates(cfg config.DatabaseConfig, args []string) error {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "rebuild") {
+		return fmt.Errorf("missing aggregates command: check or rebuild")
+	}
+
+	ctx := context.Background()
+
+	database, err := db.NewDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if args[0] == "rebuild" {
+		rows, err := database.RebuildMonthlySpend(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("monthly spend rebuilt: %d rows\n", rows)
+		return nil
+	}
+
+	mismatched, err := database.CheckMonthlySpend(ctx)
+	if err != nil {
+		return err
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("monthly spend has %d mismatched rows, run aggregates rebuild", mismatched)
+	}
+
+	fmt.Println("monthly spend is consistent")
+	return nil
+}
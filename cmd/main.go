@@ -2,17 +2,33 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"test/auth"
+	"test/cache"
+	"test/config"
 	"test/db"
 	_ "test/docs"
+	"test/graphqlapi"
+	"test/grpcserver"
 	"test/handlers"
+	"test/logging"
+	"test/metrics"
+	"test/ratelimit"
 	"test/routes"
+	"test/scheduler"
 	"test/services"
+	"test/tracing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
+	"google.golang.org/grpc"
 )
 
 // @title        Subscriptions API
@@ -21,18 +37,103 @@ import (
 // @description Позволяет создавать, обновлять, удалять и получать информацию о подписках.
 // @host         localhost:4001
 // @BasePath     /
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT в формате "Bearer <token>"
+// @securityDefinitions.apikey  APIKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API-ключ со scope read, write или admin
 func main() {
-	db, err := db.NewDB()
+	configPath := flag.String("config", os.Getenv("config_file"), "путь к YAML-файлу конфигурации")
+	printConfig := flag.Bool("print-config", false, "вывести итоговую конфигурацию без секретов и выйти")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	if *printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			fatal("failed to print config", err)
+		}
+		fmt.Print(out)
+		return
+	}
+
+	switch command := flag.Arg(0); command {
+	case "", "serve":
+		serve(cfg)
+	case "migrate":
+		if err := runMigrate(cfg.Database, flag.Args()[1:]); err != nil {
+			fatal("migrate failed", err)
+		}
+	case "aggregates":
+		if err := runAggregates(cfg.Database, flag.Args()[1:]); err != nil {
+			fatal("aggregates failed", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [flags] <command>
+
+Commands:
+  serve                  запустить HTTP-сервер (по умолчанию)
+  migrate up             применить все миграции
+  migrate down [N]       откатить N последних миграций (по умолчанию 1)
+  migrate version        показать текущую версию схемы
+  migrate force <V>      записать версию V и снять флаг dirty
+  aggregates check       сверить помесячный агрегат стоимости с подписками
+  aggregates rebuild     перестроить помесячный агрегат стоимости
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+// serve запускает HTTP-сервер и фоновые задачи и блокируется до сигнала остановки.
+func serve(cfg config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
 	if err != nil {
-		log.Fatal(err)
+		fatal("failed to configure tracing", err)
 	}
 
-	defer db.Close()
+	db, err := db.NewDB(ctx, cfg.Database)
+	if err != nil {
+		fatal("failed to open database", err)
+	}
 
-	port := os.Getenv("port")
-	if port == "" {
-		port = "4001"
+	metrics.RegisterDBStats(db.GetDB().DB, "primary")
+	if replica := db.GetReplicaDB(); replica != nil {
+		metrics.RegisterDBStats(replica.DB, "replica")
 	}
+	metrics.RegisterSubscriptionGauges(db)
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
@@ -46,15 +147,332 @@ func main() {
 		DisableStartupMessage: false,
 	})
 
-	app.Use(logger.New())
+	app.Use(tracing.Middleware())
+	app.Use(logging.RequestID())
+	app.Use(logging.AccessLog())
+	app.Use(metrics.Middleware())
+
+	apiKeyService := services.NewAPIKeyService(db)
+	jobs := scheduler.NewScheduler(db)
 
-	subscriptionService := services.NewSubscriptionService(db)
+	// Лимит по IP стоит до аутентификации: он ограничивает и запросы с неверными учётными данными,
+	// и проверки API-ключей в БД. Лимиты клиентов и групп маршрутов применяются после неё.
+	var rateLimit, ipRateLimit *ratelimit.Config
+	if cfg.RateLimit.Disabled {
+		slog.Warn("rate limiting is disabled")
+	} else {
+		rateLimit, ipRateLimit, err = newRateLimits(cfg.RateLimit, db, jobs)
+		if err != nil {
+			fatal("failed to configure rate limiting", err)
+		}
+		app.Use(ratelimit.New(*ipRateLimit))
+	}
+
+	// Проверка токенов и API-ключей общая для REST и gRPC
+	var grpcInterceptors []grpc.UnaryServerInterceptor
+	if ipRateLimit != nil {
+		grpcInterceptors = append(grpcInterceptors, ratelimit.UnaryServerInterceptor(*ipRateLimit))
+	}
+
+	if cfg.Auth.Disabled {
+		slog.Warn("authentication is disabled")
+	} else {
+		verifier, err := newVerifier(cfg.Auth)
+		if err != nil {
+			fatal("failed to configure authentication", err)
+		}
+		app.Use(auth.New(verifier, apiKeyService, cfg.Auth.PublicRoutes, graphqlapi.ReadRoutes))
+		grpcInterceptors = append(grpcInterceptors,
+			auth.UnaryServerInterceptor(verifier, apiKeyService, grpcserver.ReadMethods, grpcserver.PublicMethods))
+	}
+
+	if rateLimit != nil {
+		app.Use(ratelimit.New(*rateLimit))
+		grpcInterceptors = append(grpcInterceptors, ratelimit.UnaryServerInterceptor(*rateLimit))
+	}
+
+	webhookService := services.NewWebhookService(db)
+	subscriptionService := services.NewSubscriptionService(db, newCache(cfg.Cache))
+
+	sinks, err := newOutboxSinks(cfg.Outbox, webhookService)
+	if err != nil {
+		fatal("failed to configure outbox sinks", err)
+	}
+	outboxRelay := services.NewOutboxRelay(db, sinks...)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
 
-	routes.Use(app, subscriptionHandler)
+	reminderService := services.NewReminderService(db, services.LogNotifier{})
+
+	registerJobs(jobs, subscriptionService, reminderService, webhookService, outboxRelay)
+	jobs.Start(context.Background())
+
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	adminHandler := handlers.NewAdminHandler(jobs)
+	healthHandler := handlers.NewHealthHandler(db, jobs)
+
+	graphqlHandler, err := graphqlapi.New(subscriptionService)
+	if err != nil {
+		fatal("failed to build graphql schema", err)
+	}
+
+	routes.Use(app, subscriptionHandler, webhookHandler, apiKeyHandler, adminHandler, healthHandler, graphqlHandler)
 
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
+	app.Get("/metrics", metrics.Handler())
+
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- app.Listen(":" + cfg.Port)
+	}()
 
-	log.Fatal(app.Listen(":" + port))
+	var grpcServer *grpcserver.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = grpcserver.New(subscriptionService, cfg.GRPCReflection, grpcInterceptors...)
+		go func() {
+			slog.Info("grpc server listening", "port", cfg.GRPCPort)
+			serverErr <- grpcServer.Serve(":" + cfg.GRPCPort)
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		slog.Error("server stopped", "error", err)
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining connections")
+	}
+
+	shutdown(cfg.ShutdownTimeout, app, grpcServer, jobs, outboxRelay, db, shutdownTracing)
+}
+
+// shutdown перестаёт принимать соединения, дожидается текущих запросов и фоновых задач
+// и закрывает пул БД. На всё отводится timeout; что не успело завершиться, прерывается.
+func shutdown(timeout time.Duration, app *fiber.App, grpcServer *grpcserver.Server, jobs *scheduler.Scheduler, outboxRelay *services.OutboxRelay, db *db.DB, shutdownTracing func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		slog.Error("shutdown failed", "step", "http", "error", err)
+	}
+
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(ctx); err != nil {
+			slog.Error("shutdown failed", "step", "grpc", "error", err)
+		}
+	}
+
+	if err := jobs.Stop(ctx); err != nil {
+		slog.Error("shutdown failed", "step", "jobs", "error", err)
+	}
+
+	if err := outboxRelay.Close(); err != nil {
+		slog.Error("shutdown failed", "step", "outbox", "error", err)
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Error("shutdown failed", "step", "db", "error", err)
+	}
 
+	// Последними, чтобы выгрузить спаны завершившихся запросов и задач
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("shutdown failed", "step", "tracing", "error", err)
+	}
+
+	slog.Info("shutdown complete")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newVerifier проверяет Bearer-токены, если задан jwt_secret или jwt_jwks_file.
+// nil означает, что принимаются только API-ключи.
+func newVerifier(cfg config.AuthConfig) (*auth.Verifier, error) {
+	if cfg.JWTSecret == "" && cfg.JWKSFile == "" {
+		slog.Info("JWT is not configured, only API keys are accepted")
+		return nil, nil
+	}
+
+	return auth.NewVerifier(auth.Config{
+		Secret:   cfg.JWTSecret,
+		JWKSFile: cfg.JWKSFile,
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+	})
+}
+
+// rateLimitExempt — маршруты проб и метрик, которые не ограничиваются: оркестратор и Prometheus
+// не должны получать 429. Для gRPC так же исключены health-проверки.
+var rateLimitExempt = append([]string{"/healthz", "/readyz", "/metrics"}, grpcserver.PublicMethods...)
+
+// newRateLimits собирает лимиты клиентов и групп маршрутов и общий лимит по IP; формат лимитов уже проверен config.Validate.
+func newRateLimits(cfg config.RateLimitConfig, db *db.DB, jobs *scheduler.Scheduler) (clients, ip *ratelimit.Config, err error) {
+	limit, err := ratelimit.ParseLimit(cfg.Default)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rate_limit.default: %w", err)
+	}
+
+	ipLimit, err := ratelimit.ParseLimit(cfg.IP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rate_limit.ip: %w", err)
+	}
+
+	var groups []ratelimit.Group
+	for prefix, value := range cfg.Routes {
+		groupLimit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rate_limit.routes[%s]: %w", prefix, err)
+		}
+		groups = append(groups, ratelimit.Group{Prefix: prefix, Limit: groupLimit})
+	}
+
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		store = ratelimit.NewPostgresStore(db)
+		jobs.Register(scheduler.Job{
+			Name:     "purge-rate-limits",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				_, err := db.PurgeRateLimitBuckets(ctx, rateLimitRetention)
+				return err
+			},
+		})
+	default:
+		return nil, nil, fmt.Errorf("unknown rate limit store: %s", cfg.Store)
+	}
+
+	clients = &ratelimit.Config{Store: store, Default: limit, Groups: groups, Exempt: rateLimitExempt}
+	ip = &ratelimit.Config{Store: store, Default: ipLimit, Exempt: rateLimitExempt, ByIP: true}
+	return clients, ip, nil
+}
+
+const (
+	rateLimitRetention   = 24 * time.Hour
+	purgeRetention       = 30 * 24 * time.Hour
+	outboxPurgeRetention = 7 * 24 * time.Hour
+	reminderWindow       = 3
+)
+
+// newCache создаёт кеш ответов GetTotalCost и ListSubscriptions; параметры уже проверены config.Validate.
+func newCache(cfg config.CacheConfig) cache.Cache {
+	if cfg.Store == "none" {
+		slog.Info("response cache is disabled")
+		return cache.Noop{}
+	}
+
+	return cache.NewLRU(cfg.Size, cfg.TTL)
+}
+
+// newOutboxSinks собирает приёмники событий outbox по именам из конфигурации.
+func newOutboxSinks(cfg config.OutboxConfig, webhookService *services.WebhookService) ([]services.EventSink, error) {
+	var sinks []services.EventSink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "webhook":
+			sinks = append(sinks, webhookService)
+		case "stdout":
+			sinks = append(sinks, services.NewStdoutSink())
+		case "file":
+			sink, err := services.NewFileSink(cfg.File)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink: %s", name)
+		}
+	}
+
+	return sinks, nil
+}
+
+func registerJobs(jobs *scheduler.Scheduler, subscriptionService *services.SubscriptionService, reminderService *services.ReminderService, webhookService *services.WebhookService, outboxRelay *services.OutboxRelay) {
+	jobs.Register(scheduler.Job{
+		Name:     "expire-subscriptions",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			expired, err := subscriptionService.ExpireSubscriptions(ctx)
+			if err == nil && expired > 0 {
+				slog.InfoContext(ctx, "subscriptions expired", "count", expired)
+			}
+			return err
+		},
+	})
+
+	jobs.Register(scheduler.Job{
+		Name:     "purge-deleted-subscriptions",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			purged, err := subscriptionService.PurgeDeletedSubscriptions(ctx, purgeRetention)
+			if err == nil && purged > 0 {
+				slog.InfoContext(ctx, "deleted subscriptions purged", "count", purged)
+			}
+			return err
+		},
+	})
+
+	// Кроме исправления расхождений сдвигает horizon, до которого развёрнуты бессрочные подписки
+	jobs.Register(scheduler.Job{
+		Name:     "rebuild-monthly-spend",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			rows, err := subscriptionService.RebuildMonthlySpend(ctx)
+			if err == nil {
+				slog.InfoContext(ctx, "monthly spend rebuilt", "rows", rows)
+			}
+			return err
+		},
+	})
+
+	jobs.Register(scheduler.Job{
+		Name:     "renewal-reminders",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			sent, err := reminderService.SendRenewalReminders(ctx, reminderWindow)
+			if sent > 0 {
+				slog.InfoContext(ctx, "renewal reminders sent", "count", sent)
+			}
+			return err
+		},
+	})
+
+	jobs.Register(scheduler.Job{
+		Name:     "webhook-deliveries",
+		Interval: 10 * time.Second,
+		Run: func(ctx context.Context) error {
+			delivered, err := webhookService.DeliverPending(ctx)
+			if delivered > 0 {
+				slog.InfoContext(ctx, "webhooks delivered", "count", delivered)
+			}
+			return err
+		},
+	})
+
+	jobs.Register(scheduler.Job{
+		Name:     "outbox-relay",
+		Interval: 5 * time.Second,
+		Run: func(ctx context.Context) error {
+			published, err := outboxRelay.Relay(ctx)
+			if published > 0 {
+				slog.InfoContext(ctx, "outbox events published", "count", published)
+			}
+			return err
+		},
+	})
+
+	jobs.Register(scheduler.Job{
+		Name:     "purge-outbox",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			purged, err := outboxRelay.PurgePublished(ctx, outboxPurgeRetention)
+			if err == nil && purged > 0 {
+				slog.InfoContext(ctx, "published outbox events purged", "count", purged)
+			}
+			return err
+		},
+	})
 }
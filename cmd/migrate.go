@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"test/config"
+	"test/db"
+)
+
+// runMigrate выполняет подкоманду migrate: up, down [N], version или force <V>.
+// Аргументы проверяются до подключения к БД.
+func runMigrate(cfg config.DatabaseConfig, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command: up, down, version or force")
+	}
+
+	var run func(*db.Migrator) error
+
+	switch args[0] {
+	case "up":
+		run = (*db.Migrator).Up
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		run = func(m *db.Migrator) error { return m.Down(steps) }
+	case "version":
+		run = printVersion
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("missing version for force")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		run = func(m *db.Migrator) error { return m.Force(version) }
+	default:
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+
+	migrator, err := db.NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return run(migrator)
+}
+
+func printVersion(migrator *db.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%d (dirty)\n", version)
+	} else {
+		fmt.Println(version)
+	}
+	return nil
+}
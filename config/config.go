@@ -0,0 +1,516 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"test/ratelimit"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v3"
+)
+
+const redacted = "REDACTED"
+
+// Config — настройки сервиса. Значения берутся по возрастанию приоритета:
+// значения по умолчанию, YAML-файл, .env, переменные окружения.
+type Config struct {
+	Port string `yaml:"port"`
+	// GRPCPort — порт gRPC-сервера; пустое значение отключает gRPC
+	GRPCPort string `yaml:"grpc_port"`
+	// GRPCReflection включает gRPC reflection: список сервисов и схемы доступны без аутентификации
+	GRPCReflection bool `yaml:"grpc_reflection"`
+	// ShutdownTimeout — сколько ждать завершения запросов и фоновых задач при остановке
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout"`
+	Log             LogConfig       `yaml:"log"`
+	Tracing         TracingConfig   `yaml:"tracing"`
+	Database        DatabaseConfig  `yaml:"database"`
+	Auth            AuthConfig      `yaml:"auth"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+	Outbox          OutboxConfig    `yaml:"outbox"`
+	Cache           CacheConfig     `yaml:"cache"`
+}
+
+type LogConfig struct {
+	// Level — debug, info, warn или error
+	Level string `yaml:"level"`
+	// Format — json или text
+	Format string `yaml:"format"`
+}
+
+type TracingConfig struct {
+	// Exporter — none, otlp (OTLP/HTTP, например локальный collector на :4318) или stdout
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name"`
+}
+
+type DatabaseConfig struct {
+	// URL (DATABASE_URL или database_url) имеет приоритет над отдельными полями подключения
+	URL      string `yaml:"url"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	RLS      bool   `yaml:"rls"`
+	// ReplicaURL — необязательная read-only реплика для списков и отчётов, пул настраивается как у primary
+	ReplicaURL string `yaml:"replica_url"`
+	// AutoMigrate применяет миграции при запуске serve; по умолчанию миграции запускаются командой migrate up
+	AutoMigrate bool       `yaml:"auto_migrate"`
+	Pool        PoolConfig `yaml:"pool"`
+}
+
+type PoolConfig struct {
+	// MaxOpenConns — 0 снимает ограничение
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectTimeout — сколько повторять подключение при старте, пока БД недоступна
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// StatementTimeout выставляется каждому соединению пула; 0 — без ограничения
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+}
+
+type AuthConfig struct {
+	Disabled     bool     `yaml:"disabled"`
+	JWTSecret    string   `yaml:"jwt_secret"`
+	JWKSFile     string   `yaml:"jwks_file"`
+	Issuer       string   `yaml:"issuer"`
+	Audience     string   `yaml:"audience"`
+	PublicRoutes []string `yaml:"public_routes"`
+}
+
+type RateLimitConfig struct {
+	Disabled bool   `yaml:"disabled"`
+	Default  string `yaml:"default"`
+	// IP — общий лимит на IP, который проверяется до аутентификации
+	IP string `yaml:"ip"`
+	// Routes — лимиты групп маршрутов: префикс -> лимит
+	Routes map[string]string `yaml:"routes"`
+	Store  string            `yaml:"store"`
+}
+
+type CacheConfig struct {
+	// Store — memory (LRU в памяти процесса) или none
+	Store string `yaml:"store"`
+	// Size — максимальное число записей
+	Size int `yaml:"size"`
+	// TTL ограничивает устаревание, когда подписки изменяет другая реплика сервиса
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type OutboxConfig struct {
+	Sinks []string `yaml:"sinks"`
+	File  string   `yaml:"file"`
+}
+
+func Default() Config {
+	return Config{
+		Port:            "4001",
+		GRPCPort:        "4002",
+		ShutdownTimeout: 30 * time.Second,
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "subscriptions",
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			SSLMode: "disable",
+			Pool: PoolConfig{
+				MaxOpenConns:     25,
+				MaxIdleConns:     5,
+				ConnMaxLifetime:  30 * time.Minute,
+				ConnMaxIdleTime:  5 * time.Minute,
+				ConnectTimeout:   time.Minute,
+				StatementTimeout: 30 * time.Second,
+			},
+		},
+		Auth: AuthConfig{
+			PublicRoutes: []string{"/swagger/*", "/healthz", "/readyz", "/metrics"},
+		},
+		RateLimit: RateLimitConfig{
+			Default: "300/1m",
+			IP:      "600/1m",
+			Routes: map[string]string{
+				"/api/v1/subscriptions/total":    "30/1m",
+				"/api/v1/subscriptions/forecast": "30/1m",
+			},
+			Store: "memory",
+		},
+		Outbox: OutboxConfig{
+			Sinks: []string{"webhook"},
+			File:  "outbox.log",
+		},
+		Cache: CacheConfig{
+			Store: "memory",
+			Size:  1000,
+			TTL:   time.Minute,
+		},
+	}
+}
+
+// Load собирает конфигурацию. path — необязательный YAML-файл, пустой path означает его отсутствие.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	if err := godotenv.Load(); err != nil {
+		slog.Debug(".env file not found")
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	setString(&c.Port, "port")
+	if value, ok := os.LookupEnv("grpc_port"); ok {
+		c.GRPCPort = value
+	}
+	errs = append(errs, setBool(&c.GRPCReflection, "grpc_reflection"))
+	errs = append(errs, setDuration(&c.ShutdownTimeout, "shutdown_timeout"))
+
+	setString(&c.Log.Level, "log_level")
+	setString(&c.Log.Format, "log_format")
+
+	setString(&c.Tracing.Exporter, "otel_exporter")
+	setString(&c.Tracing.Endpoint, "otel_endpoint")
+	errs = append(errs, setBool(&c.Tracing.Insecure, "otel_insecure"))
+	errs = append(errs, setFloat(&c.Tracing.SampleRatio, "otel_sample_ratio"))
+	setString(&c.Tracing.ServiceName, "otel_service_name")
+
+	setString(&c.Database.URL, "DATABASE_URL", "database_url")
+	setString(&c.Database.Host, "db_host")
+	setString(&c.Database.Port, "db_port")
+	setString(&c.Database.User, "db_user")
+	setString(&c.Database.Password, "db_password")
+	setString(&c.Database.Name, "db_name")
+	setString(&c.Database.SSLMode, "db_sslmode")
+	setString(&c.Database.ReplicaURL, "DATABASE_REPLICA_URL", "database_replica_url")
+	errs = append(errs, setBool(&c.Database.RLS, "db_rls"))
+	errs = append(errs, setBool(&c.Database.AutoMigrate, "db_auto_migrate"))
+	errs = append(errs, setInt(&c.Database.Pool.MaxOpenConns, "db_max_open_conns"))
+	errs = append(errs, setInt(&c.Database.Pool.MaxIdleConns, "db_max_idle_conns"))
+	errs = append(errs, setDuration(&c.Database.Pool.ConnMaxLifetime, "db_conn_max_lifetime"))
+	errs = append(errs, setDuration(&c.Database.Pool.ConnMaxIdleTime, "db_conn_max_idle_time"))
+	errs = append(errs, setDuration(&c.Database.Pool.ConnectTimeout, "db_connect_timeout"))
+	errs = append(errs, setDuration(&c.Database.Pool.StatementTimeout, "db_statement_timeout"))
+
+	errs = append(errs, setBool(&c.Auth.Disabled, "auth_disabled"))
+	setString(&c.Auth.JWTSecret, "jwt_secret")
+	setString(&c.Auth.JWKSFile, "jwt_jwks_file")
+	setString(&c.Auth.Issuer, "jwt_issuer")
+	setString(&c.Auth.Audience, "jwt_audience")
+	setList(&c.Auth.PublicRoutes, "auth_public_routes")
+
+	errs = append(errs, setBool(&c.RateLimit.Disabled, "rate_limit_disabled"))
+	setString(&c.RateLimit.Default, "rate_limit")
+	setString(&c.RateLimit.IP, "rate_limit_ip")
+	setString(&c.RateLimit.Store, "rate_limit_store")
+	if value := os.Getenv("rate_limit_routes"); value != "" {
+		routes := make(map[string]string)
+		for _, route := range splitList(value) {
+			prefix, limit, ok := strings.Cut(route, "=")
+			if !ok {
+				errs = append(errs, fmt.Errorf("rate_limit_routes: invalid entry %q", route))
+				continue
+			}
+			routes[prefix] = limit
+		}
+		c.RateLimit.Routes = routes
+	}
+
+	setList(&c.Outbox.Sinks, "outbox_sinks")
+	setString(&c.Outbox.File, "outbox_file")
+
+	setString(&c.Cache.Store, "cache_store")
+	errs = append(errs, setInt(&c.Cache.Size, "cache_size"))
+	errs = append(errs, setDuration(&c.Cache.TTL, "cache_ttl"))
+
+	return errors.Join(errs...)
+}
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки сразу.
+func (c Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: invalid value %q", c.Port))
+	}
+
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("grpc_port: invalid value %q", c.GRPCPort))
+		} else if c.GRPCPort == c.Port {
+			errs = append(errs, errors.New("grpc_port: must differ from port"))
+		}
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout: must be positive"))
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: invalid value %q", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("log.format: invalid value %q", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: unknown exporter %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio: must be between 0 and 1"))
+	}
+
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = append(errs, errors.New("database.url: must be a postgres:// URL"))
+		}
+	} else {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host: required when database.url is not set"))
+		}
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("database.user: required when database.url is not set"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name: required when database.url is not set"))
+		}
+		switch c.Database.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			errs = append(errs, fmt.Errorf("database.sslmode: invalid value %q", c.Database.SSLMode))
+		}
+	}
+
+	if c.Database.ReplicaURL != "" {
+		if u, err := url.Parse(c.Database.ReplicaURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = append(errs, errors.New("database.replica_url: must be a postgres:// URL"))
+		}
+	}
+
+	pool := c.Database.Pool
+	if pool.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("database.pool.max_open_conns: must not be negative"))
+	}
+	if pool.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database.pool.max_idle_conns: must not be negative"))
+	}
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		errs = append(errs, errors.New("database.pool.max_idle_conns: must not exceed max_open_conns"))
+	}
+	if pool.ConnMaxLifetime < 0 || pool.ConnMaxIdleTime < 0 || pool.StatementTimeout < 0 {
+		errs = append(errs, errors.New("database.pool: durations must not be negative"))
+	}
+	if pool.ConnectTimeout < 0 {
+		errs = append(errs, errors.New("database.pool.connect_timeout: must not be negative"))
+	}
+
+	if !c.RateLimit.Disabled {
+		if _, err := ratelimit.ParseLimit(c.RateLimit.Default); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.default: %w", err))
+		}
+		if _, err := ratelimit.ParseLimit(c.RateLimit.IP); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.ip: %w", err))
+		}
+		for prefix, limit := range c.RateLimit.Routes {
+			if _, err := ratelimit.ParseLimit(limit); err != nil {
+				errs = append(errs, fmt.Errorf("rate_limit.routes[%s]: %w", prefix, err))
+			}
+		}
+		if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+			errs = append(errs, fmt.Errorf("rate_limit.store: unknown store %q", c.RateLimit.Store))
+		}
+	}
+
+	for _, sink := range c.Outbox.Sinks {
+		if sink != "webhook" && sink != "stdout" && sink != "file" {
+			errs = append(errs, fmt.Errorf("outbox.sinks: unknown sink %q", sink))
+		}
+	}
+
+	switch c.Cache.Store {
+	case "none":
+	case "memory":
+		if c.Cache.Size <= 0 {
+			errs = append(errs, errors.New("cache.size: must be positive"))
+		}
+		if c.Cache.TTL <= 0 {
+			errs = append(errs, errors.New("cache.ttl: must be positive"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cache.store: unknown store %q", c.Cache.Store))
+	}
+
+	return errors.Join(errs...)
+}
+
+// DSN возвращает строку подключения к Postgres.
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     d.Host + ":" + d.Port,
+		Path:     "/" + d.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(d.SSLMode),
+	}
+	return u.String()
+}
+
+// Redacted возвращает копию конфигурации, безопасную для вывода в лог.
+func (c Config) Redacted() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = redacted
+	}
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err == nil {
+			c.Database.URL = u.Redacted()
+		} else {
+			c.Database.URL = redacted
+		}
+	}
+	if c.Database.ReplicaURL != "" {
+		if u, err := url.Parse(c.Database.ReplicaURL); err == nil {
+			c.Database.ReplicaURL = u.Redacted()
+		} else {
+			c.Database.ReplicaURL = redacted
+		}
+	}
+	if c.Auth.JWTSecret != "" {
+		c.Auth.JWTSecret = redacted
+	}
+	return c
+}
+
+// YAML сериализует конфигурацию с замаскированными секретами.
+func (c Config) YAML() (string, error) {
+	data, err := yaml.Marshal(c.Redacted())
+	return string(data), err
+}
+
+func setString(target *string, names ...string) {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			*target = value
+			return
+		}
+	}
+}
+
+func setBool(target *bool, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func setInt(target *int, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func setFloat(target *float64, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func setDuration(target *time.Duration, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func setList(target *[]string, name string) {
+	if value := os.Getenv(name); value != "" {
+		*target = splitList(value)
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"test/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// monthlySpendMonths — на сколько месяцев после текущего разворачиваются бессрочные подписки.
+// Периоды дальше horizon GetTotalCost считает по подпискам.
+const monthlySpendMonths = 24
+
+// monthlySpendRows строит строки агрегата monthly_spend до horizon ($1). Месяц оплачивается, если
+// оплачиваемое время подписки в нём не покрыто целиком одной паузой — по тому же правилу totalCostQuery
+// учитывает подписку в периоде; prev_month — предыдущий оплачиваемый месяц той же подписки.
+// where дополняет условие отбора подписок.
+func monthlySpendRows(where string) string {
+	return `
+		SELECT organization_id, user_id, service_name, month,
+		       COALESCE(prev_month, '-infinity'::date) AS prev_month, SUM(price)::bigint AS total
+		FROM (
+			SELECT organization_id, user_id, service_name, price, m.month,
+			       LAG(m.month) OVER (PARTITION BY subscription.id ORDER BY m.month) AS prev_month
+			FROM subscriptions.subscription` + billingStart + `
+			CROSS JOIN LATERAL (
+				SELECT d::date AS month, (d + INTERVAL '1 month - 1 day')::date AS month_end
+				FROM generate_series(
+					date_trunc('month', billing.paid_start::timestamp),
+					LEAST(COALESCE(end_date::date, $1::date), $1::date)::timestamp,
+					INTERVAL '1 month'
+				) AS d
+			) AS m
+			WHERE deleted_at IS NULL
+			  AND (end_date IS NULL OR end_date::date >= GREATEST(billing.paid_start, m.month))
+			  AND NOT EXISTS (
+				SELECT 1 FROM subscriptions.subscription_pause p
+				WHERE p.subscription_id = subscription.id
+				  AND p.paused_at::date <= GREATEST(billing.paid_start, m.month)
+				  AND (p.resumed_at IS NULL OR p.resumed_at::date > LEAST(COALESCE(end_date::date, m.month_end), m.month_end))
+			  )` + where + `
+		) AS billed
+		GROUP BY organization_id, user_id, service_name, month, prev_month`
+}
+
+// refreshMonthlySpend пересчитывает строки агрегата группы подписки (организация, пользователь, сервис)
+// в транзакции, изменившей подписку. До первой полной перестройки агрегат не ведётся.
+func refreshMonthlySpend(ctx context.Context, tx *sqlx.Tx, subscription models.Subscription) error {
+	// Пока агрегат не построен, изменения подписок не должны ждать блокировок
+	horizon, err := monthlySpendHorizon(ctx, tx)
+	if err != nil || !horizon.Valid {
+		return err
+	}
+
+	// Ждём завершения RebuildMonthlySpend, чтобы не записать строки по устаревшему horizon
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE subscriptions.monthly_spend IN ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	// Параллельные изменения одной группы пересчитываются по очереди, иначе вставки конфликтуют
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('monthly_spend:' || $1 || ':' || $2 || ':' || $3))`,
+		subscription.OrganizationID, subscription.UserID.String(), subscription.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	// Пока ждали блокировку, RebuildMonthlySpend мог сдвинуть horizon
+	if horizon, err = monthlySpendHorizon(ctx, tx); err != nil || !horizon.Valid {
+		return err
+	}
+
+	group := []interface{}{subscription.OrganizationID, subscription.UserID, subscription.ServiceName}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM subscriptions.monthly_spend WHERE organization_id = $1 AND user_id = $2 AND service_name = $3`, group...)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO subscriptions.monthly_spend (organization_id, user_id, service_name, month, prev_month, total)` +
+		monthlySpendRows(" AND organization_id = $2 AND user_id = $3 AND service_name = $4")
+	_, err = tx.ExecContext(ctx, query, append([]interface{}{horizon.Time.Format(models.DateLayout)}, group...)...)
+	return err
+}
+
+func monthlySpendHorizon(ctx context.Context, tx *sqlx.Tx) (sql.NullTime, error) {
+	var horizon sql.NullTime
+	err := tx.GetContext(ctx, &horizon, `SELECT horizon FROM subscriptions.monthly_spend_state`)
+	return horizon, err
+}
+
+// monthlySpendAvailable сообщает, что GetTotalCost можно посчитать по агрегату:
+// нужен период из целых месяцев в пределах horizon. На таком периоде prorate не меняет итог.
+func monthlySpendAvailable(ctx context.Context, tx *sqlx.Tx, req *models.TotalCostRequest) (bool, error) {
+	if !wholeMonths(req.PeriodStart, req.PeriodEnd) {
+		return false, nil
+	}
+
+	var ok bool
+	err := tx.GetContext(ctx, &ok, `SELECT COALESCE($1::date <= horizon, false) FROM subscriptions.monthly_spend_state`, req.PeriodEnd)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("monthly_spend", true))
+	return true, nil
+}
+
+// monthlySpendTotalQuery считает GetTotalCost по агрегату за период [$2, $1].
+// Каждая подписка учитывается один раз — в первом оплачиваемом месяце внутри периода.
+func monthlySpendTotalQuery(columns string) string {
+	return `
+		SELECT ` + columns + `COALESCE(SUM(total), 0)::bigint AS total
+		FROM subscriptions.monthly_spend
+		WHERE month BETWEEN $2::date AND $1::date
+		  AND prev_month < $2::date`
+}
+
+// wholeMonths сообщает, что период начинается первым и заканчивается последним днём месяца.
+func wholeMonths(start, end string) bool {
+	startDate, err := time.Parse(models.DateLayout, start)
+	if err != nil || startDate.Day() != 1 {
+		return false
+	}
+
+	endDate, err := time.Parse(models.DateLayout, end)
+	return err == nil && endDate.AddDate(0, 0, 1).Day() == 1
+}
+
+// RebuildMonthlySpend пересчитывает агрегат целиком и сдвигает horizon на monthlySpendMonths месяцев вперёд.
+func (db *DB) RebuildMonthlySpend(ctx context.Context) (int64, error) {
+	ctx, done := observeQuery(ctx, "RebuildMonthlySpend")
+	defer done()
+
+	var rows int64
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE subscriptions.monthly_spend IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		var horizon time.Time
+		err := tx.GetContext(ctx, &horizon, `
+		UPDATE subscriptions.monthly_spend_state
+		SET horizon = (date_trunc('month', CURRENT_DATE) + make_interval(months => $1 + 1) - INTERVAL '1 day')::date,
+		    rebuilt_at = NOW()
+		RETURNING horizon`, monthlySpendMonths)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions.monthly_spend`); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `INSERT INTO subscriptions.monthly_spend (organization_id, user_id, service_name, month, prev_month, total)`+monthlySpendRows(""), horizon.Format(models.DateLayout))
+		if err != nil {
+			return err
+		}
+
+		rows, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return rows, nil
+}
+
+// CheckMonthlySpend сравнивает агрегат с пересчётом по подпискам и возвращает число расходящихся строк.
+func (db *DB) CheckMonthlySpend(ctx context.Context) (int, error) {
+	ctx, done := observeQuery(ctx, "CheckMonthlySpend")
+	defer done()
+
+	query := `
+	WITH expected AS (` + monthlySpendRows("") + `),
+	actual AS (
+		SELECT organization_id, user_id, service_name, month, prev_month, total FROM subscriptions.monthly_spend
+	)
+	SELECT COUNT(*) FROM (
+		(SELECT * FROM expected EXCEPT ALL SELECT * FROM actual)
+		UNION ALL
+		(SELECT * FROM actual EXCEPT ALL SELECT * FROM expected)
+	) AS diff`
+
+	var mismatched int
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		horizon, err := monthlySpendHorizon(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if !horizon.Valid {
+			return errors.New("monthly spend aggregate has not been built yet")
+		}
+
+		return tx.GetContext(ctx, &mismatched, query, horizon.Time.Format(models.DateLayout))
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return mismatched, nil
+}
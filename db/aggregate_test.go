@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"test/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestWholeMonths(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       bool
+	}{
+		{"2025-01-01", "2025-01-31", true},
+		{"2025-01-01", "2025-12-31", true},
+		{"2024-02-01", "2024-02-29", true},
+		{"2025-02-01", "2025-02-28", true},
+		{"2025-01-02", "2025-01-31", false},
+		{"2025-01-01", "2025-01-30", false},
+		{"2025-02-01", "2025-02-27", false},
+		{"01-2025", "2025-01-31", false},
+		{"2025-01-01", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.start+".."+tt.end, func(t *testing.T) {
+			if got := wholeMonths(tt.start, tt.end); got != tt.want {
+				t.Errorf("wholeMonths(%q, %q) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMonthlySpendMatchesSubscriptions считает итог за все периоды из целых месяцев
+// по агрегату и по подпискам и проверяет, что они совпадают.
+func TestMonthlySpendMatchesSubscriptions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	organizationID := "test-" + uuid.NewString()
+	userID := uuid.New()
+	scope := models.Scope{OrganizationID: organizationID}
+	t.Cleanup(func() { cleanupOrganization(t, db, organizationID) })
+
+	date := func(value string) *string { return &value }
+
+	type pause struct{ from, to string }
+	fixtures := []struct {
+		subscription models.Subscription
+		pauses       []pause
+	}{
+		// Две паузы подряд: февраль целиком покрыт первой, март — второй, поэтому ни один из них не оплачивается
+		{models.Subscription{ServiceName: "two-pauses", Price: 100, StartDate: "2025-01-01"},
+			[]pause{{"2025-02-01", "2025-03-01"}, {"2025-03-01", "2025-04-01"}}},
+		{models.Subscription{ServiceName: "trial", Price: 200, StartDate: "2025-01-10", TrialEndDate: date("2025-02-15")}, nil},
+		{models.Subscription{ServiceName: "ended", Price: 300, StartDate: "2024-11-01", EndDate: date("2025-01-20")}, nil},
+		{models.Subscription{ServiceName: "short-pause", Price: 400, StartDate: "2025-03-15"},
+			[]pause{{"2025-05-10", "2025-05-20"}}},
+		{models.Subscription{ServiceName: "open-pause", Price: 500, StartDate: "2024-12-01", EndDate: date("2025-07-31")},
+			[]pause{{"2025-04-01", ""}}},
+		{models.Subscription{ServiceName: "ends-paused", Price: 600, StartDate: "2025-01-01", EndDate: date("2025-03-10")},
+			[]pause{{"2025-03-01", ""}}},
+	}
+
+	for _, fixture := range fixtures {
+		subscription := fixture.subscription
+		subscription.OrganizationID = organizationID
+		subscription.UserID = userID
+		if err := db.CreateSubscription(ctx, &subscription); err != nil {
+			t.Fatalf("create %s: %v", subscription.ServiceName, err)
+		}
+
+		for _, p := range fixture.pauses {
+			var resumedAt *string
+			if p.to != "" {
+				resumedAt = &p.to
+			}
+			_, err := db.GetDB().ExecContext(ctx,
+				`INSERT INTO subscriptions.subscription_pause (subscription_id, paused_at, resumed_at) VALUES ($1, $2, $3)`,
+				subscription.ID, p.from, resumedAt)
+			if err != nil {
+				t.Fatalf("pause %s: %v", subscription.ServiceName, err)
+			}
+		}
+	}
+
+	if _, err := db.RebuildMonthlySpend(ctx); err != nil {
+		t.Fatalf("rebuild monthly spend: %v", err)
+	}
+
+	first := time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC)
+	const months = 12
+
+	err := db.inTx(ctx, organizationID, func(tx *sqlx.Tx) error {
+		for i := 0; i < months; i++ {
+			for j := i; j < months; j++ {
+				req := &models.TotalCostRequest{
+					PeriodStart: first.AddDate(0, i, 0).Format(models.DateLayout),
+					PeriodEnd:   first.AddDate(0, j+1, -1).Format(models.DateLayout),
+				}
+
+				available, err := monthlySpendAvailable(ctx, tx, req)
+				if err != nil {
+					return err
+				}
+				if !available {
+					return fmt.Errorf("aggregate is not available for %s..%s", req.PeriodStart, req.PeriodEnd)
+				}
+
+				var aggregated, raw int
+				query, args := totalCostQuery(scope, req, true, "")
+				if err := tx.GetContext(ctx, &aggregated, query, args...); err != nil {
+					return err
+				}
+				query, args = totalCostQuery(scope, req, false, "")
+				if err := tx.GetContext(ctx, &raw, query, args...); err != nil {
+					return err
+				}
+
+				if aggregated != raw {
+					t.Errorf("%s..%s: aggregate %d, subscriptions %d", req.PeriodStart, req.PeriodEnd, aggregated, raw)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Каждый месяц покрыт своей паузой, поэтому за февраль и март подписка не оплачивается,
+	// хотя ни одна пауза не покрывает весь период
+	total, err := db.GetTotalCost(ctx, scope, &models.TotalCostRequest{
+		PeriodStart: "2025-02-01", PeriodEnd: "2025-03-31", ServiceName: date("two-pauses"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 {
+		t.Errorf("two-pauses total for 2025-02..2025-03 = %d, want 0", total)
+	}
+}
+
+func cleanupOrganization(t *testing.T, db *DB, organizationID string) {
+	t.Helper()
+
+	queries := []string{
+		`DELETE FROM subscriptions.subscription_pause WHERE subscription_id IN (SELECT id FROM subscriptions.subscription WHERE organization_id = $1)`,
+		`DELETE FROM subscriptions.outbox WHERE aggregate_id IN (SELECT id FROM subscriptions.subscription WHERE organization_id = $1)`,
+		`DELETE FROM subscriptions.subscription WHERE organization_id = $1`,
+		`DELETE FROM subscriptions.monthly_spend WHERE organization_id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := db.GetDB().ExecContext(context.Background(), query, organizationID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"test/models"
+)
+
+func (db *DB) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	ctx, done := observeQuery(ctx, "CreateAPIKey")
+	defer done()
+
+	query := `
+	INSERT INTO subscriptions.api_key (organization_id, name, prefix, key_hash, scopes, user_id, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING *`
+
+	return db.conn.QueryRowxContext(ctx, query, key.OrganizationID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.UserID, key.ExpiresAt).StructScan(key)
+}
+
+func (db *DB) ListAPIKeys(ctx context.Context, organizationID string) ([]models.APIKey, error) {
+	ctx, done := observeQuery(ctx, "ListAPIKeys")
+	defer done()
+
+	keys := []models.APIKey{}
+	err := db.conn.SelectContext(ctx, &keys, `SELECT * FROM subscriptions.api_key WHERE organization_id = $1 ORDER BY id`, organizationID)
+	return keys, err
+}
+
+func (db *DB) RevokeAPIKey(ctx context.Context, organizationID string, id int) error {
+	ctx, done := observeQuery(ctx, "RevokeAPIKey")
+	defer done()
+
+	query := `UPDATE subscriptions.api_key SET revoked_at = NOW() WHERE id = $1 AND organization_id = $2 AND revoked_at IS NULL`
+	result, err := db.conn.ExecContext(ctx, query, id, organizationID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+
+	return nil
+}
+
+// GetActiveAPIKeyByHash возвращает неотозванный и неистёкший ключ по хешу.
+func (db *DB) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	ctx, done := observeQuery(ctx, "GetActiveAPIKeyByHash")
+	defer done()
+
+	query := `
+	SELECT * FROM subscriptions.api_key
+	WHERE key_hash = $1
+	  AND revoked_at IS NULL
+	  AND (expires_at IS NULL OR expires_at > NOW())`
+
+	var key models.APIKey
+	err := db.conn.GetContext(ctx, &key, query, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return key, errors.New("api key not found")
+		}
+		return key, err
+	}
+
+	return key, nil
+}
+
+func (db *DB) TouchAPIKey(ctx context.Context, id int) error {
+	ctx, done := observeQuery(ctx, "TouchAPIKey")
+	defer done()
+
+	_, err := db.conn.ExecContext(ctx, `UPDATE subscriptions.api_key SET last_used_at = NOW() WHERE id = $1`, id)
+	return err
+}
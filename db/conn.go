@@ -1,55 +1,172 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"test/config"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/XSAM/otelsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	connectBackoffMin = 500 * time.Millisecond
+	connectBackoffMax = 10 * time.Second
 )
 
 type DB struct {
 	conn *sqlx.DB
+	rls  bool
+
+	// replica — необязательная read-only реплика, см. readTx
+	replica          *sqlx.DB
+	replicaDownUntil atomic.Int64
+
+	expectedMigration uint
 }
 
-func NewDB() (*DB, error) {
-	err := godotenv.Load()
+// NewDB открывает пул соединений и ждёт, пока БД станет доступна, но не дольше pool.connect_timeout.
+func NewDB(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
+	db, err := openPool(cfg.DSN(), cfg.Pool)
 	if err != nil {
-		log.Println(".env file not found in InitDB")
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
-	username := os.Getenv("db_user")
-	password := os.Getenv("db_password")
-	dbName := os.Getenv("db_name")
-	dbHost := os.Getenv("db_host")
-	dbPort := os.Getenv("db_port")
-	sslmode := os.Getenv("db_sslmode")
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", username, password, dbHost, dbPort, dbName, sslmode)
+	if err := waitForDB(ctx, db.DB, cfg.Pool.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if cfg.AutoMigrate {
+		if err := runMigrations(cfg); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
 
-	db, err := sqlx.Connect("pgx", connStr)
+	expectedMigration, err := latestMigrationVersion()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	slog.Info("database connection established",
+		"max_open_conns", cfg.Pool.MaxOpenConns,
+		"max_idle_conns", cfg.Pool.MaxIdleConns,
+		"statement_timeout", cfg.Pool.StatementTimeout,
+	)
+
+	if cfg.RLS {
+		slog.Info("row-level security enabled: app.organization_id is set for tenant queries")
+	}
+
+	result := &DB{conn: db, rls: cfg.RLS, expectedMigration: expectedMigration}
+
+	if cfg.ReplicaURL != "" {
+		// Недоступная реплика не мешает старту: чтения уходят в primary, пока она не поднимется
+		replica, err := openPool(cfg.ReplicaURL, cfg.Pool)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open replica: %w", err)
+		}
+		if err := replica.PingContext(ctx); err != nil {
+			slog.Warn("replica is not available, reads fall back to primary", "error", err)
+			result.markReplicaDown()
+		} else {
+			slog.Info("replica connection established")
+		}
+		result.replica = replica
+	}
+
+	return result, nil
+}
+
+// openPool создаёт пул соединений. Соединения устанавливаются лениво, при первом запросе.
+func openPool(dsn string, pool config.PoolConfig) (*sqlx.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database connection string: %w", err)
 	}
 
-	if err := runMigrations(connStr); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	// Параметры сессии передаются при установке каждого соединения пула,
+	// поэтому действуют и после переподключения
+	connConfig.RuntimeParams["search_path"] = "subscriptions, public"
+	if pool.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(pool.StatementTimeout.Milliseconds(), 10)
 	}
 
-	if _, err := db.Exec("SET search_path TO subscriptions, public"); err != nil {
-		return nil, fmt.Errorf("failed to set search_path: %w", err)
+	// otelsql создаёт спан на каждый SQL-запрос внутри спана метода репозитория
+	sqlDB, err := otelsql.Open("pgx", stdlib.RegisterConnConfig(connConfig),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Println("DB connection is established =)")
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-	return &DB{conn: db}, nil
+	return sqlx.NewDb(sqlDB, "pgx"), nil
+}
+
+// waitForDB повторяет Ping с экспоненциальной задержкой, пока БД не ответит или не истечёт timeout.
+// Нулевой timeout означает одну попытку.
+func waitForDB(ctx context.Context, sqlDB *sql.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return sqlDB.PingContext(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := connectBackoffMin
+	for attempt := 1; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("database is not available, retrying", "attempt", attempt, "retry_in", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		backoff = min(backoff*2, connectBackoffMax)
+	}
 }
 
 func (db *DB) Close() error {
+	if db.replica != nil {
+		db.replica.Close()
+	}
 	return db.conn.Close()
 }
 
 func (db *DB) GetDB() *sqlx.DB {
 	return db.conn
 }
+
+// GetReplicaDB возвращает пул реплики или nil, если реплика не настроена.
+func (db *DB) GetReplicaDB() *sqlx.DB {
+	return db.replica
+}
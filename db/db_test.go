@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"os"
+	"test/config"
+	"testing"
+)
+
+// newTestDB подключается к Postgres из TEST_DATABASE_URL и применяет миграции.
+// Без переменной тесты, которым нужна БД, пропускаются.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	cfg := config.Default().Database
+	cfg.URL = url
+	cfg.AutoMigrate = true
+
+	db, err := NewDB(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"test/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LeaderLock — сессионный advisory lock лидера планировщика. Он держится на одном выделенном
+// соединении пула, пока реплика остаётся лидером, независимо от числа задач.
+type LeaderLock struct {
+	conn *sqlx.Conn
+	name string
+}
+
+// TryLockLeader пытается стать лидером name. Если lock держит другая реплика, acquired — false.
+func (db *DB) TryLockLeader(ctx context.Context, name string) (lock *LeaderLock, acquired bool, err error) {
+	conn, err := db.conn.Connx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.GetContext(ctx, &acquired, `SELECT pg_try_advisory_lock(hashtext('leader:' || $1))`, name); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return &LeaderLock{conn: conn, name: name}, true, nil
+}
+
+// Check проверяет, что соединение с lock живо. При ошибке lock потерян вместе с сессией,
+// и его нужно закрыть через Unlock.
+func (l *LeaderLock) Check(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Unlock снимает lock и возвращает соединение в пул. Если lock уже не удерживался, возвращается ошибка.
+func (l *LeaderLock) Unlock(ctx context.Context) error {
+	defer l.conn.Close()
+
+	var released bool
+	if err := l.conn.GetContext(ctx, &released, `SELECT pg_advisory_unlock(hashtext('leader:' || $1))`, l.name); err != nil {
+		// Соединение закрывается с ошибкой, поэтому пул его не переиспользует, и сессионный lock снимется вместе с ним
+		l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		return err
+	}
+	if !released {
+		return errors.New("leader lock " + l.name + " was not held")
+	}
+	return nil
+}
+
+// JobRunDue сообщает, прошло ли interval с последнего успешного запуска задачи на любой из реплик.
+func (db *DB) JobRunDue(ctx context.Context, name string, interval time.Duration) (bool, error) {
+	ctx, done := observeQuery(ctx, "JobRunDue")
+	defer done()
+
+	query := `
+	SELECT NOT EXISTS (
+		SELECT 1 FROM subscriptions.job_run
+		WHERE name = $1 AND last_finished_at > NOW() - make_interval(secs => $2)
+	)`
+
+	var due bool
+	err := db.conn.GetContext(ctx, &due, query, name, interval.Seconds())
+	return due, err
+}
+
+func (db *DB) StartJobRun(ctx context.Context, name, instance string) error {
+	ctx, done := observeQuery(ctx, "StartJobRun")
+	defer done()
+
+	query := `
+	INSERT INTO subscriptions.job_run (name, last_started_at, last_instance)
+	VALUES ($1, NOW(), $2)
+	ON CONFLICT (name) DO UPDATE SET last_started_at = NOW(), last_instance = $2`
+
+	_, err := db.conn.ExecContext(ctx, query, name, instance)
+	return err
+}
+
+func (db *DB) FinishJobRun(ctx context.Context, name string, runErr error) error {
+	ctx, done := observeQuery(ctx, "FinishJobRun")
+	defer done()
+
+	var lastError *string
+	if runErr != nil {
+		message := runErr.Error()
+		lastError = &message
+	}
+
+	query := `
+	UPDATE subscriptions.job_run
+	SET last_finished_at = NOW(), last_error = $2, run_count = run_count + 1
+	WHERE name = $1`
+
+	_, err := db.conn.ExecContext(ctx, query, name, lastError)
+	return err
+}
+
+func (db *DB) ListJobRuns(ctx context.Context) ([]models.JobRun, error) {
+	ctx, done := observeQuery(ctx, "ListJobRuns")
+	defer done()
+
+	var runs []models.JobRun
+	err := db.conn.SelectContext(ctx, &runs, `SELECT * FROM subscriptions.job_run ORDER BY name`)
+	return runs, err
+}
+
+// PurgeDeletedSubscriptions окончательно удаляет подписки, помеченные удалёнными раньше olderThan.
+func (db *DB) PurgeDeletedSubscriptions(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, done := observeQuery(ctx, "PurgeDeletedSubscriptions")
+	defer done()
+
+	query := `DELETE FROM subscriptions.subscription WHERE deleted_at < NOW() - make_interval(secs => $1)`
+
+	var purged int64
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, olderThan.Seconds())
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+	return purged, err
+}
+
+// ListDueRenewals возвращает ближайшие списания по активным подпискам в течение within дней,
+// о которых ещё не отправлялось напоминание.
+func (db *DB) ListDueRenewals(ctx context.Context, within int) ([]models.RenewalReminder, error) {
+	ctx, done := observeQuery(ctx, "ListDueRenewals")
+	defer done()
+
+	query := `
+		SELECT id AS subscription_id, organization_id, user_id, service_name, price, to_char(charge.date, 'YYYY-MM-DD') AS charge_date
+		FROM subscriptions.subscription` + billingStart + chargeDates("CURRENT_DATE", "CURRENT_DATE + $1::int") + `
+		WHERE deleted_at IS NULL
+		  AND status = 'active'
+		  AND charge.date BETWEEN CURRENT_DATE AND CURRENT_DATE + $1::int
+		  AND (end_date IS NULL OR charge.date <= end_date::date)
+		  AND NOT EXISTS (
+			SELECT 1 FROM subscriptions.renewal_reminder r
+			WHERE r.subscription_id = subscription.id AND r.charge_date = to_char(charge.date, 'YYYY-MM-DD')
+		  )
+		ORDER BY charge.date, id`
+
+	var reminders []models.RenewalReminder
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &reminders, query, within)
+	})
+	return reminders, err
+}
+
+func (db *DB) MarkRenewalReminded(ctx context.Context, reminder models.RenewalReminder) error {
+	ctx, done := observeQuery(ctx, "MarkRenewalReminded")
+	defer done()
+
+	query := `
+	INSERT INTO subscriptions.renewal_reminder (subscription_id, charge_date)
+	VALUES ($1, $2)
+	ON CONFLICT (subscription_id, charge_date) DO NOTHING`
+
+	_, err := db.conn.ExecContext(ctx, query, reminder.SubscriptionID, reminder.ChargeDate)
+	return err
+}
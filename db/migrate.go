@@ -1,41 +1,154 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"strconv"
+	"strings"
+	"test/config"
+	"test/migrations"
+	"test/models"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-func runMigrations(databaseURL string) error {
-	m, err := migrate.New(
-		"file://migrations",
-		databaseURL,
-	)
+// Migrator применяет встроенные в бинарник миграции. Конкурентные запуски с нескольких реплик
+// сериализуются advisory lock драйвера postgres.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+func NewMigrator(cfg config.DatabaseConfig) (*Migrator, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up применяет все ещё не применённые миграции.
+func (mg *Migrator) Up() error {
+	versionBefore, _, err := mg.Version()
+	if err != nil {
+		return err
+	}
+
+	if err := mg.m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			slog.Info("migrations already up to date", "version", versionBefore)
+			return nil
+		}
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	versionAfter, _, _ := mg.Version()
+	slog.Info("migrations applied", "from", versionBefore, "to", versionAfter)
+
+	return nil
+}
+
+// Down откатывает steps последних миграций.
+func (mg *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive")
+	}
+
+	versionBefore, _, err := mg.Version()
 	if err != nil {
-		return fmt.Errorf("failed to create migrator: %w", err)
+		return err
 	}
-	defer m.Close()
 
-	versionBefore, _, errBefore := m.Version()
-	if errBefore != nil && errBefore != migrate.ErrNilVersion {
-		versionBefore = 0
+	if err := mg.m.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
 	}
 
-	migrationErr := m.Up()
-	if migrationErr != nil && migrationErr != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", migrationErr)
+	versionAfter, _, _ := mg.Version()
+	slog.Info("migrations rolled back", "from", versionBefore, "to", versionAfter)
+
+	return nil
+}
+
+// Version возвращает применённую версию схемы; 0 — миграции ещё не применялись.
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
 	}
 
-	versionAfter, _, _ := m.Version()
+	return version, dirty, nil
+}
 
-	if migrationErr == migrate.ErrNoChange || (versionBefore > 0 && versionBefore == versionAfter) {
-		log.Println("Migrations: already up to date")
-	} else {
-		log.Println("Migrations: applied successfully")
+// Force записывает версию схемы и снимает флаг dirty, не выполняя миграций.
+// Нужен после ручного исправления упавшей миграции.
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version: %w", err)
 	}
 
+	slog.Info("schema version forced", "version", version)
 	return nil
 }
+
+func (mg *Migrator) Close() error {
+	sourceErr, dbErr := mg.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// latestMigrationVersion возвращает номер последней миграции, встроенной в бинарник.
+func latestMigrationVersion() (uint, error) {
+	files, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s", file)
+		}
+		latest = max(latest, uint(version))
+	}
+
+	return latest, nil
+}
+
+// MigrationStatus сравнивает применённую версию схемы с последней миграцией, известной бинарнику.
+func (db *DB) MigrationStatus(ctx context.Context) (models.MigrationStatus, error) {
+	var status models.MigrationStatus
+	if err := db.conn.GetContext(ctx, &status, `SELECT version, dirty FROM schema_migrations LIMIT 1`); err != nil {
+		return status, err
+	}
+
+	status.Expected = db.expectedMigration
+	return status, nil
+}
+
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
+}
+
+func runMigrations(cfg config.DatabaseConfig) error {
+	migrator, err := NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
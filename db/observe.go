@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"test/metrics"
+	"test/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// observeQuery открывает спан метода репозитория (спаны отдельных SQL-запросов создаёт otelsql внутри него),
+// а по завершении записывает длительность в метрики и debug-лог вместе с request_id из ctx.
+//
+//	ctx, done := observeQuery(ctx, "GetSubscription")
+//	defer done()
+func observeQuery(ctx context.Context, method string) (context.Context, func()) {
+	started := time.Now()
+	ctx, span := tracing.Start(ctx, "db."+method, trace.WithSpanKind(trace.SpanKindInternal))
+
+	return ctx, func() {
+		span.End()
+		metrics.ObserveQuery(method, started)
+		slog.DebugContext(ctx, "db query", "method", method, "duration_ms", time.Since(started).Milliseconds())
+	}
+}
+
+// spanError отмечает спан из ctx как ошибочный. Отсутствие строки ошибкой не считается.
+func spanError(ctx context.Context, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"test/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertOutboxEvent пишет событие в outbox в той же транзакции, что и изменение подписки,
+// поэтому событие не теряется, если процесс упадёт после коммита.
+func insertOutboxEvent(ctx context.Context, tx *sqlx.Tx, eventType string, subscription models.Subscription) error {
+	payload, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO subscriptions.outbox (event_type, aggregate_id, payload) VALUES ($1, $2, $3)`
+	_, err = tx.ExecContext(ctx, query, eventType, subscription.ID, string(payload))
+	return err
+}
+
+// ListUnpublishedEvents возвращает события, время попытки которых наступило, по порядку. События подписки,
+// у которой более раннее событие уже не удалось отправить, не выбираются: они ждут, пока то не отправится
+// или не исчерпает попытки.
+func (db *DB) ListUnpublishedEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	ctx, done := observeQuery(ctx, "ListUnpublishedEvents")
+	defer done()
+
+	query := `
+	SELECT * FROM subscriptions.outbox o
+	WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+	  AND NOT EXISTS (
+		SELECT 1 FROM subscriptions.outbox e
+		WHERE e.aggregate_id = o.aggregate_id AND e.id < o.id
+		  AND e.published_at IS NULL AND e.failed_at IS NULL AND e.attempts > 0
+	  )
+	ORDER BY id
+	LIMIT $1`
+
+	var events []models.OutboxEvent
+	err := db.conn.SelectContext(ctx, &events, query, limit)
+	return events, err
+}
+
+func (db *DB) MarkEventPublished(ctx context.Context, id int64) error {
+	ctx, done := observeQuery(ctx, "MarkEventPublished")
+	defer done()
+
+	query := `UPDATE subscriptions.outbox SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1`
+	_, err := db.conn.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkEventFailed записывает неудачную попытку и приёмники, которые событие уже получили.
+// Следующая попытка будет не раньше nextAttempt; если nextAttempt nil, событие больше не отправляется.
+func (db *DB) MarkEventFailed(ctx context.Context, id int64, publishedSinks models.StringList, publishErr error, nextAttempt *time.Time) error {
+	ctx, done := observeQuery(ctx, "MarkEventFailed")
+	defer done()
+
+	query := `
+	UPDATE subscriptions.outbox
+	SET attempts = attempts + 1, last_error = $2, published_sinks = $3,
+	    next_attempt_at = COALESCE($4, next_attempt_at),
+	    failed_at = CASE WHEN $4::timestamptz IS NULL THEN NOW() END
+	WHERE id = $1`
+	_, err := db.conn.ExecContext(ctx, query, id, publishErr.Error(), publishedSinks, nextAttempt)
+	return err
+}
+
+func (db *DB) PurgePublishedEvents(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, done := observeQuery(ctx, "PurgePublishedEvents")
+	defer done()
+
+	query := `DELETE FROM subscriptions.outbox WHERE published_at < NOW() - make_interval(secs => $1)`
+
+	result, err := db.conn.ExecContext(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"test/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestFailedEventWaitsForNextAttempt(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	organizationID := "test-" + uuid.NewString()
+	t.Cleanup(func() { cleanupOrganization(t, db, organizationID) })
+
+	subscription := models.Subscription{OrganizationID: organizationID, UserID: uuid.New(), ServiceName: "outbox", Price: 100, StartDate: "2025-01-01"}
+	if err := db.CreateSubscription(ctx, &subscription); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pending := func() *models.OutboxEvent {
+		t.Helper()
+		events, err := db.ListUnpublishedEvents(ctx, 1000)
+		if err != nil {
+			t.Fatalf("list events: %v", err)
+		}
+		for _, event := range events {
+			if event.AggregateID == subscription.ID {
+				return &event
+			}
+		}
+		return nil
+	}
+
+	event := pending()
+	if event == nil {
+		t.Fatal("created event is not pending")
+	}
+
+	publishErr := errors.New("sink unavailable")
+
+	next := time.Now().Add(time.Hour)
+	if err := db.MarkEventFailed(ctx, event.ID, nil, publishErr, &next); err != nil {
+		t.Fatalf("mark failed: %v", err)
+	}
+	if pending() != nil {
+		t.Error("event is retried before next_attempt_at")
+	}
+
+	past := time.Now().Add(-time.Second)
+	if err := db.MarkEventFailed(ctx, event.ID, nil, publishErr, &past); err != nil {
+		t.Fatalf("mark failed: %v", err)
+	}
+	retried := pending()
+	if retried == nil || retried.FailedAt != nil || retried.Attempts != 2 {
+		t.Fatalf("event is not retried after next_attempt_at: %+v", retried)
+	}
+
+	if err := db.MarkEventFailed(ctx, event.ID, nil, publishErr, nil); err != nil {
+		t.Fatalf("mark dead: %v", err)
+	}
+	if pending() != nil {
+		t.Error("dead event is still pending")
+	}
+}
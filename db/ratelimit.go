@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// refilledTokens — число токенов в корзине с учётом восполнения с момента последнего запроса.
+// Текущее время берётся из EXCLUDED.updated_at: clock_timestamp() вычисляется один раз в VALUES,
+// поэтому tokens, allowed и updated_at считаются от одного и того же момента.
+const refilledTokens = `LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM EXCLUDED.updated_at - b.updated_at)::float8 * $3::float8)`
+
+// TakeRateLimitToken атомарно восполняет корзину key и берёт из неё токен, если он есть.
+// Новая корзина создаётся полной. Возвращает оставшиеся токены и признак, что запрос разрешён.
+func (db *DB) TakeRateLimitToken(ctx context.Context, key string, capacity, perSecond float64) (float64, bool, error) {
+	ctx, done := observeQuery(ctx, "TakeRateLimitToken")
+	defer done()
+
+	query := `
+	INSERT INTO subscriptions.rate_limit_bucket AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, $2::float8 - 1, TRUE, clock_timestamp())
+	ON CONFLICT (key) DO UPDATE SET
+	    tokens = CASE WHEN ` + refilledTokens + ` >= 1 THEN ` + refilledTokens + ` - 1 ELSE ` + refilledTokens + ` END,
+	    allowed = ` + refilledTokens + ` >= 1,
+	    updated_at = EXCLUDED.updated_at
+	RETURNING tokens, allowed`
+
+	var tokens float64
+	var allowed bool
+	err := db.conn.QueryRowxContext(ctx, query, key, capacity, perSecond).Scan(&tokens, &allowed)
+	return tokens, allowed, err
+}
+
+// PurgeRateLimitBuckets удаляет корзины, к которым не обращались дольше olderThan.
+func (db *DB) PurgeRateLimitBuckets(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, done := observeQuery(ctx, "PurgeRateLimitBuckets")
+	defer done()
+
+	query := `DELETE FROM subscriptions.rate_limit_bucket WHERE updated_at < NOW() - make_interval(secs => $1)`
+
+	result, err := db.conn.ExecContext(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replicaRetryAfter — сколько чтения идут в primary после ошибки реплики.
+const replicaRetryAfter = 30 * time.Second
+
+type primaryReadsKey struct{}
+
+// WithPrimaryReads направляет все чтения запроса в primary, чтобы клиент
+// видел свои только что сделанные изменения несмотря на отставание реплики.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// PrimaryReads сообщает, что запрос должен читать актуальные данные из primary.
+func PrimaryReads(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return forced
+}
+
+type replicaReadsKey struct{}
+
+// TrackReplicaReads помечает ctx, чтобы узнать, читал ли запрос с реплики. Возвращённая функция
+// сообщает, обслужила ли реплика хотя бы одно чтение с этим ctx.
+func TrackReplicaReads(ctx context.Context) (context.Context, func() bool) {
+	served := new(atomic.Bool)
+	return context.WithValue(ctx, replicaReadsKey{}, served), served.Load
+}
+
+// readTx выполняет тяжёлое чтение (списки, итоги, отчёты) в read-only транзакции на реплике.
+// Без реплики, при WithPrimaryReads или после недавнего сбоя реплики запрос идёт в primary.
+// Если реплика недоступна, запрос повторяется в primary. Ошибки самого запроса (синтаксис, данные,
+// statement_timeout, отмена) возвращаются как есть: в primary они повторились бы так же.
+func (db *DB) readTx(ctx context.Context, organizationID string, fn func(tx *sqlx.Tx) error) error {
+	if db.replica == nil || PrimaryReads(ctx) || !db.replicaAvailable() {
+		return db.inTx(ctx, organizationID, fn)
+	}
+
+	err := db.runTx(ctx, db.replica, &sql.TxOptions{ReadOnly: true}, organizationID, fn)
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		if served, ok := ctx.Value(replicaReadsKey{}).(*atomic.Bool); ok {
+			served.Store(true)
+		}
+		return err
+	}
+	if ctx.Err() != nil || !isConnectionError(err) {
+		return err
+	}
+
+	db.markReplicaDown()
+	slog.WarnContext(ctx, "replica query failed, falling back to primary", "error", err)
+	trace.SpanFromContext(ctx).AddEvent("replica fallback")
+
+	return db.inTx(ctx, organizationID, fn)
+}
+
+// isConnectionError сообщает, что запрос не выполнился из-за соединения с БД, а не из-за самого запроса.
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "08"): // connection_exception
+			return true
+		case pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03": // сервер останавливается или запускается
+			return true
+		case pgErr.Code == "53300": // too_many_connections
+			return true
+		}
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func (db *DB) replicaAvailable() bool {
+	return time.Now().UnixNano() >= db.replicaDownUntil.Load()
+}
+
+func (db *DB) markReplicaDown() {
+	db.replicaDownUntil.Store(time.Now().Add(replicaRetryAfter).UnixNano())
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, want: true},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "connection done", err: sql.ErrConnDone, want: true},
+		{name: "unexpected eof", err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, want: true},
+		{name: "cannot connect now", err: &pgconn.PgError{Code: "57P03"}, want: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "wrapped connection failure", err: fmt.Errorf("list: %w", &pgconn.PgError{Code: "08003"}), want: true},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: false},
+		{name: "statement timeout", err: &pgconn.PgError{Code: "57014"}, want: false},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, want: false},
+		{name: "undefined column", err: &pgconn.PgError{Code: "42703"}, want: false},
+		{name: "invalid date", err: &pgconn.PgError{Code: "22007"}, want: false},
+		{name: "division by zero", err: &pgconn.PgError{Code: "22012"}, want: false},
+		{name: "no rows", err: sql.ErrNoRows, want: false},
+		{name: "scan error", err: errors.New("sql: Scan error on column index 0"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
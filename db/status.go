@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"test/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UpdateSubscriptionStatus переводит подписку из статуса from в to.
+// Условие на текущий статус защищает от гонок между параллельными запросами.
+func (db *DB) UpdateSubscriptionStatus(ctx context.Context, id int, scope models.Scope, from, to models.SubscriptionStatus) (models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "UpdateSubscriptionStatus")
+	defer done()
+
+	filter, args := scopeFilter(scope, []interface{}{to, id, from})
+
+	query := `
+	UPDATE subscriptions.subscription
+	SET status = $1,
+	    end_date = CASE
+	        WHEN $1 = 'cancelled' AND (end_date IS NULL OR end_date::date > CURRENT_DATE)
+	        THEN to_char(CURRENT_DATE, 'YYYY-MM-DD')
+	        ELSE end_date
+	    END,
+	    updated_at = NOW()
+	WHERE id = $2 AND status = $3 AND deleted_at IS NULL` + filter + `
+	RETURNING *
+	`
+
+	var subscription models.Subscription
+
+	err := db.inTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		err := tx.QueryRowxContext(ctx, query, args...).StructScan(&subscription)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: %s -> %s", models.ErrInvalidStatusTransition, from, to)
+			}
+			return err
+		}
+
+		switch {
+		case to == models.StatusPaused:
+			_, err = tx.ExecContext(ctx, `INSERT INTO subscriptions.subscription_pause (subscription_id, paused_at) VALUES ($1, to_char(CURRENT_DATE, 'YYYY-MM-DD'))`, id)
+		case from == models.StatusPaused:
+			// Пауза закрывается при любом выходе из неё: возобновлении, отмене или истечении
+			err = closePauses(ctx, tx, []int{id})
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := refreshMonthlySpend(ctx, tx, subscription); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, models.EventSubscriptionUpdated, subscription)
+	})
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// closePauses закрывает открытые паузы подписок subscriptionIDs текущей датой.
+func closePauses(ctx context.Context, tx *sqlx.Tx, subscriptionIDs []int) error {
+	query := `
+	UPDATE subscriptions.subscription_pause
+	SET resumed_at = to_char(CURRENT_DATE, 'YYYY-MM-DD')
+	WHERE subscription_id = ANY($1::int[]) AND resumed_at IS NULL`
+
+	_, err := tx.ExecContext(ctx, query, subscriptionIDs)
+	return err
+}
+
+// ListSubscriptionPauses возвращает паузы подписок subscriptionIDs в хронологическом порядке.
+func (db *DB) ListSubscriptionPauses(ctx context.Context, scope models.Scope, subscriptionIDs []int) ([]models.SubscriptionPause, error) {
+	ctx, done := observeQuery(ctx, "ListSubscriptionPauses")
+	defer done()
+
+	filter, args := scopeFilter(scope, []interface{}{subscriptionIDs})
+	query := `
+	SELECT p.id, p.subscription_id, p.paused_at, p.resumed_at
+	FROM subscriptions.subscription_pause p
+	JOIN subscriptions.subscription ON subscription.id = p.subscription_id
+	WHERE p.subscription_id = ANY($1::int[])` + filter + `
+	ORDER BY p.subscription_id, p.paused_at, p.id
+	`
+
+	var pauses []models.SubscriptionPause
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &pauses, query, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pauses, nil
+}
+
+// ExpireSubscriptions переводит в expired все подписки, у которых прошла end_date, и возвращает их.
+func (db *DB) ExpireSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "ExpireSubscriptions")
+	defer done()
+
+	query := `
+	UPDATE subscriptions.subscription
+	SET status = 'expired', updated_at = NOW()
+	WHERE status IN ('active', 'paused')
+	  AND end_date IS NOT NULL
+	  AND end_date::date < CURRENT_DATE
+	  AND deleted_at IS NULL
+	RETURNING *
+	`
+
+	var subscriptions []models.Subscription
+
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		if err := tx.SelectContext(ctx, &subscriptions, query); err != nil {
+			return err
+		}
+
+		ids := make([]int, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			ids = append(ids, subscription.ID)
+		}
+		if err := closePauses(ctx, tx, ids); err != nil {
+			return err
+		}
+
+		for _, subscription := range subscriptions {
+			if err := insertOutboxEvent(ctx, tx, models.EventSubscriptionExpired, subscription); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// CountSubscriptionsByStatus считает неудалённые подписки всех организаций по статусам.
+func (db *DB) CountSubscriptionsByStatus(ctx context.Context) (map[string]int, error) {
+	ctx, done := observeQuery(ctx, "CountSubscriptionsByStatus")
+	defer done()
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+
+	query := `SELECT status, COUNT(*) AS count FROM subscriptions.subscription WHERE deleted_at IS NULL GROUP BY status`
+	err := db.inTx(ctx, models.SystemOrganization, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &rows, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"test/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCancelClosesOpenPause(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	organizationID := "test-" + uuid.NewString()
+	scope := models.Scope{OrganizationID: organizationID}
+	t.Cleanup(func() { cleanupOrganization(t, db, organizationID) })
+
+	subscription := models.Subscription{OrganizationID: organizationID, UserID: uuid.New(), ServiceName: "paused", Price: 100, StartDate: "2025-01-01"}
+	if err := db.CreateSubscription(ctx, &subscription); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := db.UpdateSubscriptionStatus(ctx, subscription.ID, scope, models.StatusActive, models.StatusPaused); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if _, err := db.UpdateSubscriptionStatus(ctx, subscription.ID, scope, models.StatusPaused, models.StatusCancelled); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	pauses, err := db.ListSubscriptionPauses(ctx, scope, []int{subscription.ID})
+	if err != nil {
+		t.Fatalf("list pauses: %v", err)
+	}
+	if len(pauses) != 1 || pauses[0].ResumedAt == nil {
+		t.Fatalf("pause is still open after cancel: %+v", pauses)
+	}
+}
@@ -1,21 +1,45 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"strconv"
 	"test/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
-func (db *DB) CreateSubscription(subscription *models.Subscription) error {
-	query := `INSERT INTO subscriptions.subscription (service_name, price, user_id, start_date, end_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	return db.conn.QueryRow(query, subscription.ServiceName, subscription.Price, subscription.UserID, subscription.StartDate, subscription.EndDate).Scan(&subscription.ID)
+func (db *DB) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	ctx, done := observeQuery(ctx, "CreateSubscription")
+	defer done()
+
+	return db.inTx(ctx, subscription.OrganizationID, func(tx *sqlx.Tx) error {
+		query := `INSERT INTO subscriptions.subscription (organization_id, service_name, price, user_id, start_date, end_date, trial_end_date) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *`
+		err := tx.QueryRowxContext(ctx, query, subscription.OrganizationID, subscription.ServiceName, subscription.Price, subscription.UserID, subscription.StartDate, subscription.EndDate, subscription.TrialEndDate).StructScan(subscription)
+		if err != nil {
+			return err
+		}
+
+		if err := refreshMonthlySpend(ctx, tx, *subscription); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, models.EventSubscriptionCreated, *subscription)
+	})
 }
 
-func (db *DB) GetSubscription(id int) (models.Subscription, error) {
+func (db *DB) GetSubscription(ctx context.Context, id int, scope models.Scope) (models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "GetSubscription")
+	defer done()
+
 	var subscription models.Subscription
-	query := `SELECT * FROM subscriptions.subscription WHERE id = $1 AND deleted_at IS NULL`
-	err := db.conn.Get(&subscription, query, id)
+	filter, args := scopeFilter(scope, []interface{}{id})
+	query := `SELECT * FROM subscriptions.subscription WHERE id = $1 AND deleted_at IS NULL` + filter
+	err := db.inTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &subscription, query, args...)
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -27,63 +51,171 @@ func (db *DB) GetSubscription(id int) (models.Subscription, error) {
 	return subscription, nil
 }
 
-func (db *DB) DeleteSubscription(id int) error {
-	query := `UPDATE subscriptions.subscription SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-	result, err := db.conn.Exec(query, id)
-	if err != nil {
-		return err
-	}
+func (db *DB) DeleteSubscription(ctx context.Context, id int, scope models.Scope) (models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "DeleteSubscription")
+	defer done()
+
+	filter, args := scopeFilter(scope, []interface{}{id})
+	query := `UPDATE subscriptions.subscription SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL` + filter + ` RETURNING *`
+
+	var subscription models.Subscription
+
+	err := db.inTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		if err := tx.QueryRowxContext(ctx, query, args...).StructScan(&subscription); err != nil {
+			return err
+		}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return errors.New("subscription not found")
+		if err := refreshMonthlySpend(ctx, tx, subscription); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, models.EventSubscriptionDeleted, subscription)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Subscription{}, errors.New("subscription not found")
+		}
+		return models.Subscription{}, err
 	}
 
-	return nil
+	return subscription, nil
 }
 
-func (db *DB) ListSubscriptions(page, limit int) ([]models.Subscription, int, error) {
+func (db *DB) ListSubscriptions(ctx context.Context, scope models.Scope, filter models.ListSubscriptionsFilter) ([]models.Subscription, int, error) {
+	ctx, done := observeQuery(ctx, "ListSubscriptions")
+	defer done()
+
 	var subscriptions []models.Subscription
 
+	scoped, args := scopeFilter(scope, nil)
+	where := " WHERE deleted_at IS NULL" + scoped
+
+	if filter.UserID != nil {
+		where += " AND user_id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, *filter.UserID)
+	}
+
+	if filter.TrialEndingWithin != nil {
+		where += " AND trial_end_date::date BETWEEN CURRENT_DATE AND CURRENT_DATE + $" + strconv.Itoa(len(args)+1) + "::int"
+		args = append(args, *filter.TrialEndingWithin)
+	}
+
 	var total int
-	countQuery := `SELECT COUNT(*) FROM subscriptions.subscription WHERE deleted_at IS NULL`
-	err := db.conn.Get(&total, countQuery)
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		countQuery := `SELECT COUNT(*) FROM subscriptions.subscription` + where
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+
+		offset := (filter.Page - 1) * filter.Limit
+		query := `SELECT * FROM subscriptions.subscription` + where +
+			" ORDER BY id asc LIMIT $" + strconv.Itoa(len(args)+1) + " OFFSET $" + strconv.Itoa(len(args)+2)
+		return tx.SelectContext(ctx, &subscriptions, query, append(args, filter.Limit, offset)...)
+	})
 	if err != nil {
 		return nil, 0, err
 	}
 
-	offset := (page - 1) * limit
-	query := `SELECT * FROM subscriptions.subscription WHERE deleted_at IS NULL ORDER BY id asc LIMIT $1 OFFSET $2`
-	err = db.conn.Select(&subscriptions, query, limit, offset)
+	return subscriptions, total, nil
+}
+
+// ListSubscriptionsByUsers возвращает одним запросом не больше perUser первых неудалённых подписок
+// каждого из пользователей userIDs.
+func (db *DB) ListSubscriptionsByUsers(ctx context.Context, scope models.Scope, userIDs []uuid.UUID, perUser int) ([]models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "ListSubscriptionsByUsers")
+	defer done()
+
+	filter, args := scopeFilter(scope, []interface{}{uuidStrings(userIDs), perUser})
+	query := `
+	SELECT s.* FROM unnest($1::uuid[]) AS u(id)
+	CROSS JOIN LATERAL (
+		SELECT * FROM subscriptions.subscription
+		WHERE user_id = u.id AND deleted_at IS NULL` + filter + `
+		ORDER BY id
+		LIMIT $2
+	) AS s
+	ORDER BY s.id`
+
+	var subscriptions []models.Subscription
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &subscriptions, query, args...)
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return subscriptions, total, nil
+	return subscriptions, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
 }
 
-func (db *DB) UpdateSubscription(id int, req *models.UpdateSubscriptionRequest) (models.Subscription, error) {
+func (db *DB) UpdateSubscription(ctx context.Context, id int, scope models.Scope, req *models.UpdateSubscriptionRequest) (models.Subscription, error) {
+	ctx, done := observeQuery(ctx, "UpdateSubscription")
+	defer done()
+
+	filter, args := scopeFilter(scope, []interface{}{
+		req.ServiceName,
+		req.Price,
+		req.StartDate,
+		req.EndDate,
+		req.TrialEndDate,
+		id,
+	})
+
 	query := `
 	UPDATE subscriptions.subscription
 	SET service_name = COALESCE($1, service_name),
 	    price = COALESCE($2, price),
 	    start_date = COALESCE($3, start_date),
 	    end_date = COALESCE($4, end_date),
+	    trial_end_date = COALESCE($5, trial_end_date),
 	    updated_at = NOW()
-	WHERE id = $5 AND deleted_at IS NULL
+	WHERE id = $6 AND deleted_at IS NULL` + filter + `
 	RETURNING *
 	`
 
 	var subscription models.Subscription
 
-	err := db.conn.QueryRowx(query,
-		req.ServiceName,
-		req.Price,
-		req.StartDate,
-		req.EndDate,
-		id,
-	).StructScan(&subscription)
+	err := db.inTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		// При смене сервиса подписка уходит из одной группы агрегата в другую, пересчитываются обе
+		var previousServiceName string
+		if req.ServiceName != nil {
+			lockFilter, lockArgs := scopeFilter(scope, []interface{}{id})
+			err := tx.GetContext(ctx, &previousServiceName,
+				`SELECT service_name FROM subscriptions.subscription WHERE id = $1 AND deleted_at IS NULL`+lockFilter+` FOR UPDATE`, lockArgs...)
+			if err != nil {
+				return err
+			}
+		}
 
+		if err := tx.QueryRowxContext(ctx, query, args...).StructScan(&subscription); err != nil {
+			return err
+		}
+
+		// Изменённые поля проверены в запросе, а их сочетание с сохранёнными — только здесь
+		if err := subscription.ValidateDates(); err != nil {
+			return err
+		}
+
+		if err := refreshMonthlySpend(ctx, tx, subscription); err != nil {
+			return err
+		}
+		if previousServiceName != "" && previousServiceName != subscription.ServiceName {
+			previous := subscription
+			previous.ServiceName = previousServiceName
+			if err := refreshMonthlySpend(ctx, tx, previous); err != nil {
+				return err
+			}
+		}
+
+		return insertOutboxEvent(ctx, tx, models.EventSubscriptionUpdated, subscription)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Subscription{}, errors.New("subscription not found")
@@ -94,18 +226,122 @@ func (db *DB) UpdateSubscription(id int, req *models.UpdateSubscriptionRequest)
 	return subscription, nil
 }
 
-func (db *DB) GetTotalCost(req *models.TotalCostRequest) (int, error) {
+// Пробный период не оплачивается, поэтому стоимость считается с billing.paid_start
+const billingStart = `
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(trial_end_date::date + 1, start_date::date) AS paid_start
+		) AS billing`
+
+// chargeDates разворачивает ежемесячные списания подписки (в день billing.paid_start)
+// в строки charge.date, покрывающие интервал между SQL-выражениями from и to.
+func chargeDates(from, to string) string {
+	return `
+		CROSS JOIN LATERAL (
+			SELECT (billing.paid_start + make_interval(months => n))::date AS date
+			FROM generate_series(
+				GREATEST(0, ` + monthIndex(from) + ` - ` + monthIndex("billing.paid_start") + ` - 1),
+				` + monthIndex(to) + ` - ` + monthIndex("billing.paid_start") + `
+			) AS n
+		) AS charge`
+}
+
+func monthIndex(date string) string {
+	return "(EXTRACT(YEAR FROM " + date + ") * 12 + EXTRACT(MONTH FROM " + date + "))::int"
+}
+
+func (db *DB) GetTotalCost(ctx context.Context, scope models.Scope, req *models.TotalCostRequest) (int, error) {
+	ctx, done := observeQuery(ctx, "GetTotalCost")
+	defer done()
+
+	var total int
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		aggregated, err := monthlySpendAvailable(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		query, args := totalCostQuery(scope, req, aggregated, "")
+		return tx.GetContext(ctx, &total, query, args...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GetTotalCostByUsers считает GetTotalCost отдельно для каждого из userIDs одним запросом.
+// req.UserID не учитывается. Пользователей без расходов за период в результате нет.
+func (db *DB) GetTotalCostByUsers(ctx context.Context, scope models.Scope, req *models.TotalCostRequest, userIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	ctx, done := observeQuery(ctx, "GetTotalCostByUsers")
+	defer done()
+
+	byUser := *req
+	byUser.UserID = nil
+
+	var rows []struct {
+		UserID uuid.UUID `db:"user_id"`
+		Total  int       `db:"total"`
+	}
+
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		aggregated, err := monthlySpendAvailable(ctx, tx, &byUser)
+		if err != nil {
+			return err
+		}
+
+		query, args := totalCostQuery(scope, &byUser, aggregated, "user_id, ")
+		query += " AND user_id = ANY($" + strconv.Itoa(len(args)+1) + "::uuid[]) GROUP BY user_id"
+		return tx.SelectContext(ctx, &rows, query, append(args, uuidStrings(userIDs))...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		totals[row.UserID] = row.Total
+	}
+	return totals, nil
+}
+
+// totalCostQuery строит запрос GetTotalCost за период [$2, $1] по агрегату или по подпискам.
+// columns добавляются в SELECT перед суммой, чтобы итог можно было сгруппировать.
+func totalCostQuery(scope models.Scope, req *models.TotalCostRequest, aggregated bool, columns string) (string, []interface{}) {
+	// Подписка учитывается, если хотя бы в одном месяце периода её оплачиваемое время не покрыто
+	// целиком одной паузой. Так же месяц считает оплачиваемым monthlySpendRows, поэтому для периодов
+	// из целых месяцев итог по подпискам совпадает с итогом по агрегату.
 	query := `
-		SELECT COALESCE(SUM(price), 0) 
-		FROM subscriptions.subscription 
+		SELECT ` + columns + `COALESCE(SUM(price), 0) AS total
+		FROM subscriptions.subscription` + billingStart + `
 		WHERE deleted_at IS NULL 
-		  AND start_date <= $1 
-		  AND (end_date IS NULL OR end_date >= $2)`
+		  AND billing.paid_start <= $1::date 
+		  AND (end_date IS NULL OR end_date::date >= GREATEST(billing.paid_start, $2::date))
+		  AND EXISTS (` + billedMonths("1") + `)`
+
+	switch {
+	case aggregated:
+		query = monthlySpendTotalQuery(columns)
+	case req.Prorate:
+		// Подписка так же учитывается один раз, но её цена умножается на долю месяца, попавшую в период:
+		// берётся наибольшая доля среди оплачиваемых месяцев. Месяц, целиком входящий в период, даёт долю 1,
+		// поэтому на периодах из целых месяцев итог совпадает с расчётом без prorate.
+		query = `
+		SELECT ` + columns + `COALESCE(ROUND(SUM(price * billed.share)), 0) AS total
+		FROM subscriptions.subscription` + billingStart + `
+		CROSS JOIN LATERAL (` + billedMonths(
+			"MAX((LEAST(m.month_end, $1::date) - GREATEST(m.month_start, $2::date) + 1)::numeric / (m.month_end - m.month_start + 1)) AS share",
+		) + `) AS billed
+		WHERE deleted_at IS NULL
+		  AND billing.paid_start <= $1::date
+		  AND (end_date IS NULL OR end_date::date >= GREATEST(billing.paid_start, $2::date))
+		  AND billed.share IS NOT NULL`
+	}
 
-	args := []interface{}{req.PeriodEnd, req.PeriodStart}
+	scoped, args := scopeFilter(scope, []interface{}{req.PeriodEnd, req.PeriodStart})
+	query += scoped
 
 	if req.UserID != nil {
-		query += " AND user_id = $3"
+		query += " AND user_id = $" + strconv.Itoa(len(args)+1)
 		args = append(args, *req.UserID)
 	}
 	if req.ServiceName != nil {
@@ -113,10 +349,69 @@ func (db *DB) GetTotalCost(req *models.TotalCostRequest) (int, error) {
 		args = append(args, *req.ServiceName)
 	}
 
-	var total int
-	err := db.conn.Get(&total, query, args...)
+	return query, args
+}
+
+// billedMonths выбирает columns по оплачиваемым месяцам подписки в периоде [$2, $1]: m — границы месяца,
+// r — оплачиваемая часть месяца внутри периода. Месяц не оплачивается, если r целиком покрыт одной паузой.
+func billedMonths(columns string) string {
+	return `
+			SELECT ` + columns + `
+			FROM generate_series(
+				date_trunc('month', GREATEST(billing.paid_start, $2::date)),
+				LEAST(COALESCE(end_date::date, $1::date), $1::date),
+				INTERVAL '1 month'
+			) AS d
+			CROSS JOIN LATERAL (
+				SELECT d::date AS month_start, (d + INTERVAL '1 month - 1 day')::date AS month_end
+			) AS m
+			CROSS JOIN LATERAL (
+				SELECT GREATEST(m.month_start, billing.paid_start, $2::date) AS lower,
+				       LEAST(m.month_end, COALESCE(end_date::date, $1::date), $1::date) AS upper
+			) AS r
+			WHERE NOT EXISTS (
+				SELECT 1 FROM subscriptions.subscription_pause p
+				WHERE p.subscription_id = subscription.id
+				  AND p.paused_at::date <= r.lower
+				  AND (p.resumed_at IS NULL OR p.resumed_at::date > r.upper)
+			)
+		`
+}
+
+// ForecastCost считает списания по активным подпискам в периоде [from, to].
+// Подписка списывается ежемесячно в день начала оплачиваемого периода.
+func (db *DB) ForecastCost(ctx context.Context, scope models.Scope, req *models.ForecastRequest, from, to string) ([]models.ForecastMonth, error) {
+	ctx, done := observeQuery(ctx, "ForecastCost")
+	defer done()
+
+	query := `
+		SELECT to_char(charge.date, 'YYYY-MM') AS month, SUM(price) AS total
+		FROM subscriptions.subscription` + billingStart + chargeDates("$1::date", "$2::date") + `
+		WHERE deleted_at IS NULL
+		  AND status = 'active'
+		  AND charge.date BETWEEN $1::date AND $2::date
+		  AND (end_date IS NULL OR charge.date <= end_date::date)`
+
+	scoped, args := scopeFilter(scope, []interface{}{from, to})
+	query += scoped
+
+	if req.UserID != nil {
+		query += " AND user_id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, *req.UserID)
+	}
+	if req.ServiceName != nil {
+		query += " AND service_name = $" + strconv.Itoa(len(args)+1)
+		args = append(args, *req.ServiceName)
+	}
+
+	query += " GROUP BY month ORDER BY month"
+
+	var months []models.ForecastMonth
+	err := db.readTx(ctx, scope.OrganizationID, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &months, query, args...)
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return total, nil
+	return months, nil
 }
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"test/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestProrateMatchesWholeMonths(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	organizationID := "test-" + uuid.NewString()
+	scope := models.Scope{OrganizationID: organizationID}
+	t.Cleanup(func() { cleanupOrganization(t, db, organizationID) })
+
+	date := func(value string) *string { return &value }
+
+	subscriptions := []models.Subscription{
+		{ServiceName: "full", Price: 310, StartDate: "2024-01-01"},
+		{ServiceName: "starts-mid-month", Price: 620, StartDate: "2025-03-15"},
+		{ServiceName: "ends-mid-month", Price: 930, StartDate: "2024-06-01", EndDate: date("2025-02-10")},
+	}
+	for _, subscription := range subscriptions {
+		subscription.OrganizationID = organizationID
+		subscription.UserID = uuid.New()
+		if err := db.CreateSubscription(ctx, &subscription); err != nil {
+			t.Fatalf("create %s: %v", subscription.ServiceName, err)
+		}
+	}
+
+	total := func(tx *sqlx.Tx, serviceName, start, end string, prorate bool) int {
+		t.Helper()
+		req := &models.TotalCostRequest{PeriodStart: start, PeriodEnd: end, ServiceName: &serviceName, Prorate: prorate}
+
+		var total int
+		query, args := totalCostQuery(scope, req, false, "")
+		if err := tx.GetContext(ctx, &total, query, args...); err != nil {
+			t.Fatalf("%s %s..%s prorate=%v: %v", serviceName, start, end, prorate, err)
+		}
+		return total
+	}
+
+	err := db.inTx(ctx, organizationID, func(tx *sqlx.Tx) error {
+		// На целых месяцах prorate не меняет итог, в том числе для подписок, начавшихся или закончившихся в середине месяца
+		periods := [][2]string{{"2025-01-01", "2025-01-31"}, {"2025-01-01", "2025-03-31"}, {"2025-02-01", "2025-02-28"}, {"2025-03-01", "2025-03-31"}}
+		for _, subscription := range subscriptions {
+			for _, period := range periods {
+				plain := total(tx, subscription.ServiceName, period[0], period[1], false)
+				prorated := total(tx, subscription.ServiceName, period[0], period[1], true)
+				if plain != prorated {
+					t.Errorf("%s %s..%s: prorate %d, without prorate %d", subscription.ServiceName, period[0], period[1], prorated, plain)
+				}
+			}
+		}
+
+		// Период, начинающийся и заканчивающийся в середине месяца, масштабирует цену долей месяца
+		tests := []struct {
+			serviceName string
+			start, end  string
+			want        int
+		}{
+			{"full", "2025-01-10", "2025-01-25", 160},
+			{"full", "2025-03-10", "2025-03-31", 220},
+			{"full", "2025-01-20", "2025-02-28", 310},
+			{"starts-mid-month", "2025-03-20", "2025-03-31", 240},
+			{"ends-mid-month", "2025-02-01", "2025-02-14", 465},
+			{"ends-mid-month", "2025-02-11", "2025-02-28", 0},
+		}
+		for _, tt := range tests {
+			if got := total(tx, tt.serviceName, tt.start, tt.end, true); got != tt.want {
+				t.Errorf("%s %s..%s: prorate total %d, want %d", tt.serviceName, tt.start, tt.end, got, tt.want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
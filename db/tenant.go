@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"test/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// inTx выполняет fn в транзакции. При включённом RLS (db_rls=true) в транзакции
+// выставляется app.organization_id, по которому политика subscription_org_isolation фильтрует строки.
+func (db *DB) inTx(ctx context.Context, organizationID string, fn func(tx *sqlx.Tx) error) error {
+	err := db.runTx(ctx, db.conn, nil, organizationID, fn)
+	spanError(ctx, err)
+	return err
+}
+
+func (db *DB) runTx(ctx context.Context, conn *sqlx.DB, opts *sql.TxOptions, organizationID string, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if db.rls {
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('app.organization_id', $1, true)`, organizationID); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scopeFilter ограничивает запрос организацией и, если задан, пользователем из scope.
+func scopeFilter(scope models.Scope, args []interface{}) (string, []interface{}) {
+	args = append(args, scope.OrganizationID)
+	filter := " AND organization_id = $" + strconv.Itoa(len(args))
+
+	if scope.UserID != nil {
+		args = append(args, *scope.UserID)
+		filter += " AND user_id = $" + strconv.Itoa(len(args))
+	}
+
+	return filter, args
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"test/models"
+	"time"
+)
+
+func (db *DB) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, done := observeQuery(ctx, "CreateWebhook")
+	defer done()
+
+	query := `INSERT INTO subscriptions.webhook (organization_id, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id, active, created_at`
+	return db.conn.QueryRowxContext(ctx, query, webhook.OrganizationID, webhook.URL, webhook.Secret, webhook.Events).
+		Scan(&webhook.ID, &webhook.Active, &webhook.CreatedAt)
+}
+
+func (db *DB) ListWebhooks(ctx context.Context, organizationID string) ([]models.Webhook, error) {
+	ctx, done := observeQuery(ctx, "ListWebhooks")
+	defer done()
+
+	webhooks := []models.Webhook{}
+	err := db.conn.SelectContext(ctx, &webhooks, `SELECT * FROM subscriptions.webhook WHERE organization_id = $1 AND deleted_at IS NULL ORDER BY id`, organizationID)
+	return webhooks, err
+}
+
+func (db *DB) DeleteWebhook(ctx context.Context, organizationID string, id int) error {
+	ctx, done := observeQuery(ctx, "DeleteWebhook")
+	defer done()
+
+	query := `UPDATE subscriptions.webhook SET active = FALSE, deleted_at = NOW() WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`
+	result, err := db.conn.ExecContext(ctx, query, id, organizationID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return errors.New("webhook not found")
+	}
+
+	return nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь для всех активных вебхуков организации, подписанных на него.
+func (db *DB) EnqueueWebhookDeliveries(ctx context.Context, organizationID, event string, payload []byte) error {
+	ctx, done := observeQuery(ctx, "EnqueueWebhookDeliveries")
+	defer done()
+
+	query := `
+	INSERT INTO subscriptions.webhook_delivery (webhook_id, event, payload)
+	SELECT id, $1, $2
+	FROM subscriptions.webhook
+	WHERE active AND deleted_at IS NULL
+	  AND organization_id = $3
+	  AND (events = '' OR $1 = ANY(string_to_array(events, ',')))`
+
+	_, err := db.conn.ExecContext(ctx, query, event, string(payload), organizationID)
+	return err
+}
+
+func (db *DB) ListPendingDeliveries(ctx context.Context, limit int) ([]models.PendingDelivery, error) {
+	ctx, done := observeQuery(ctx, "ListPendingDeliveries")
+	defer done()
+
+	query := `
+	SELECT d.*, w.url, w.secret
+	FROM subscriptions.webhook_delivery d
+	JOIN subscriptions.webhook w ON w.id = d.webhook_id
+	WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.deleted_at IS NULL
+	ORDER BY d.next_attempt_at, d.id
+	LIMIT $1`
+
+	var deliveries []models.PendingDelivery
+	err := db.conn.SelectContext(ctx, &deliveries, query, limit)
+	return deliveries, err
+}
+
+// RecordDeliveryAttempt сохраняет результат попытки. Если nextAttempt nil, доставка больше не повторяется.
+func (db *DB) RecordDeliveryAttempt(ctx context.Context, id int, status string, responseStatus *int, lastError *string, nextAttempt *time.Time) error {
+	ctx, done := observeQuery(ctx, "RecordDeliveryAttempt")
+	defer done()
+
+	query := `
+	UPDATE subscriptions.webhook_delivery
+	SET status = $2,
+	    attempts = attempts + 1,
+	    response_status = $3,
+	    last_error = $4,
+	    next_attempt_at = COALESCE($5, next_attempt_at),
+	    delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE delivered_at END
+	WHERE id = $1`
+
+	_, err := db.conn.ExecContext(ctx, query, id, status, responseStatus, lastError, nextAttempt)
+	return err
+}
+
+func (db *DB) ListWebhookDeliveries(ctx context.Context, organizationID string, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	ctx, done := observeQuery(ctx, "ListWebhookDeliveries")
+	defer done()
+
+	query := `
+	SELECT d.* FROM subscriptions.webhook_delivery d
+	JOIN subscriptions.webhook w ON w.id = d.webhook_id
+	WHERE d.webhook_id = $1 AND w.organization_id = $2
+	ORDER BY d.id DESC LIMIT $3`
+
+	deliveries := []models.WebhookDelivery{}
+	err := db.conn.SelectContext(ctx, &deliveries, query, webhookID, organizationID, limit)
+	return deliveries, err
+}
@@ -0,0 +1,137 @@
+// Package graphqlapi отдаёт подписки, пользователей и итоги через GraphQL на /graphql.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"test/auth"
+	"test/models"
+	"test/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxDepth ограничивает вложенность запроса: user → subscriptions → user → ... иначе не имеет предела.
+	maxDepth = 8
+	// maxParallelism — сколько элементов списка резолвятся одновременно. Загрузчики объединяют в пакет
+	// только одновременные обращения, поэтому значение не меньше максимального limit страницы списка.
+	maxParallelism = 100
+	// maxUsers — сколько пользователей можно запросить в users(ids)
+	maxUsers = 100
+	// maxUserSubscriptions — сколько подписок пользователя отдаёт User.subscriptions
+	maxUserSubscriptions = 100
+	// maxBatchSize — сколько ключей загрузчик отправляет в БД одним запросом
+	maxBatchSize = 100
+)
+
+// ReadRoutes — маршруты GraphQL. API-ключу со scope read они доступны при любом методе:
+// scope write требуется только для мутаций и проверяется при их выполнении.
+var ReadRoutes = []string{"/graphql"}
+
+type Handler struct {
+	schema              *graphql.Schema
+	subscriptionService *services.SubscriptionService
+}
+
+func New(subscriptionService *services.SubscriptionService) (*Handler, error) {
+	schema, err := graphql.ParseSchema(schemaSDL, &resolver{subscriptionService: subscriptionService},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxParallelism(maxParallelism),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{schema: schema, subscriptionService: subscriptionService}, nil
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handle выполняет GraphQL-запрос из тела POST или из параметров query, operationName и variables GET.
+// Мутации принимаются только через POST и требуют у API-ключа scope write, запросам достаточно read.
+func (h *Handler) Handle(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	var req request
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return c.Status(400).JSON(models.ErrorResponse{
+					Status:  false,
+					Message: "invalid variables: " + err.Error(),
+				})
+			}
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if req.Query == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "query is required",
+		})
+	}
+
+	state := &requestState{
+		scope:    scope,
+		readOnly: c.Method() == fiber.MethodGet,
+		canWrite: true,
+		loaders:  newLoaders(h.subscriptionService, scope),
+	}
+	if claims, ok := auth.ClaimsFromCtx(c); ok {
+		state.canWrite = claims.AllowsScope(models.ScopeWrite)
+	}
+	ctx := context.WithValue(c.UserContext(), stateKey{}, state)
+
+	return c.JSON(h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+type stateKey struct{}
+
+// requestState — данные одного HTTP-запроса, общие для всех резолверов.
+type requestState struct {
+	scope    models.Scope
+	readOnly bool
+	// canWrite — у API-ключа есть scope write
+	canWrite bool
+	loaders  *loaders
+}
+
+func stateFromContext(ctx context.Context) *requestState {
+	return ctx.Value(stateKey{}).(*requestState)
+}
+
+// mutationState возвращает состояние запроса, если в нём разрешены мутации.
+func mutationState(ctx context.Context) (*requestState, error) {
+	state := stateFromContext(ctx)
+	if state.readOnly {
+		return nil, &apiError{code: "METHOD_NOT_ALLOWED", message: "mutations are only allowed over POST"}
+	}
+	if !state.canWrite {
+		return nil, &apiError{code: "FORBIDDEN", message: "api key lacks " + models.ScopeWrite + " scope"}
+	}
+	return state, nil
+}
@@ -0,0 +1,112 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"test/auth"
+	"test/models"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+type fakeAPIKeys map[string]models.APIKey
+
+func (f fakeAPIKeys) LookupAPIKey(ctx context.Context, rawKey string) (models.APIKey, error) {
+	if key, ok := f[rawKey]; ok {
+		return key, nil
+	}
+	return models.APIKey{}, errors.New("api key not found")
+}
+
+// TestMutationScopes проверяет, что scope API-ключа определяется типом операции, а не HTTP-методом.
+// Мутации отклоняются до обращения к сервису, поэтому он не нужен.
+func TestMutationScopes(t *testing.T) {
+	handler, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := fakeAPIKeys{
+		"read":  {ID: 1, Scopes: models.StringList{models.ScopeRead}},
+		"write": {ID: 2, Scopes: models.StringList{models.ScopeWrite}},
+	}
+	app := fiber.New()
+	app.Use(auth.New(nil, keys, nil, ReadRoutes))
+	app.Get("/graphql", handler.Handle)
+	app.Post("/graphql", handler.Handle)
+
+	const mutation = `mutation { deleteSubscription(id: 1) }`
+
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "read key mutation over post", method: "POST", key: "read", wantStatus: 200, wantCode: "FORBIDDEN"},
+		{name: "read key mutation over get", method: "GET", key: "read", wantStatus: 200, wantCode: "METHOD_NOT_ALLOWED"},
+		{name: "write key without read", method: "POST", key: "write", wantStatus: 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/graphql?query="+url.QueryEscape(mutation), nil)
+			if tt.method == "POST" {
+				body, _ := json.Marshal(map[string]string{"query": mutation})
+				req = httptest.NewRequest(tt.method, "/graphql", strings.NewReader(string(body)))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var body struct {
+				Errors []struct {
+					Extensions struct {
+						Code string `json:"code"`
+					} `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Errors) != 1 || body.Errors[0].Extensions.Code != tt.wantCode {
+				t.Errorf("errors = %+v, want code %s", body.Errors, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUsersLimit(t *testing.T) {
+	ids := make([]graphql.ID, maxUsers+1)
+	for i := range ids {
+		ids[i] = graphql.ID(uuid.NewString())
+	}
+
+	// Лишние id отклоняются до разбора и обращения к загрузчикам, поэтому состояние запроса не нужно
+	_, err := (&queryResolver{}).Users(context.Background(), struct{ IDs []graphql.ID }{IDs: ids})
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.code != "BAD_REQUEST" {
+		t.Errorf("error = %v, want BAD_REQUEST", err)
+	}
+}
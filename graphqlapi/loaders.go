@@ -0,0 +1,96 @@
+package graphqlapi
+
+import (
+	"context"
+	"test/models"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// totalKey — параметры итога пользователя. Ключи с одинаковыми параметрами считаются одним запросом.
+type totalKey struct {
+	start       string
+	end         string
+	serviceName string
+	prorate     bool
+	userID      uuid.UUID
+}
+
+// loaders собирают обращения резолверов одного запроса в пакеты, чтобы вложенные поля
+// (user → subscriptions → history) выполняли по запросу к БД на уровень, а не на каждый объект.
+// Создаются на каждый HTTP-запрос и кешируют результаты до его конца. Пакет содержит не больше
+// maxBatchSize ключей, поэтому списки в IN-условиях ограничены независимо от формы запроса.
+type loaders struct {
+	subscriptions *dataloader.Loader[uuid.UUID, []models.Subscription]
+	history       *dataloader.Loader[int, []models.SubscriptionPause]
+	totals        *dataloader.Loader[totalKey, int]
+}
+
+// batchService — пакетные методы services.SubscriptionService, которыми пользуются загрузчики.
+type batchService interface {
+	ListSubscriptionsByUsers(ctx context.Context, scope models.Scope, userIDs []uuid.UUID, perUser int) (map[uuid.UUID][]models.Subscription, error)
+	ListSubscriptionPauses(ctx context.Context, scope models.Scope, subscriptionIDs []int) (map[int][]models.SubscriptionPause, error)
+	GetTotalCostByUsers(ctx context.Context, scope models.Scope, req *models.TotalCostRequest, userIDs []uuid.UUID) (map[uuid.UUID]int, error)
+}
+
+func newLoaders(service batchService, scope models.Scope) *loaders {
+	subscriptions := func(ctx context.Context, userIDs []uuid.UUID) []*dataloader.Result[[]models.Subscription] {
+		byUser, err := service.ListSubscriptionsByUsers(ctx, scope, userIDs, maxUserSubscriptions)
+		return results(userIDs, byUser, err)
+	}
+
+	history := func(ctx context.Context, subscriptionIDs []int) []*dataloader.Result[[]models.SubscriptionPause] {
+		bySubscription, err := service.ListSubscriptionPauses(ctx, scope, subscriptionIDs)
+		return results(subscriptionIDs, bySubscription, err)
+	}
+
+	totals := func(ctx context.Context, keys []totalKey) []*dataloader.Result[int] {
+		groups := make(map[totalKey][]uuid.UUID)
+		for _, key := range keys {
+			params := key
+			params.userID = uuid.Nil
+			groups[params] = append(groups[params], key.userID)
+		}
+
+		byKey := make(map[totalKey]int, len(keys))
+		for params, userIDs := range groups {
+			req := &models.TotalCostRequest{PeriodStart: params.start, PeriodEnd: params.end, Prorate: params.prorate}
+			if params.serviceName != "" {
+				req.ServiceName = &params.serviceName
+			}
+
+			byUser, err := service.GetTotalCostByUsers(ctx, scope, req, userIDs)
+			if err != nil {
+				return results[totalKey, int](keys, nil, err)
+			}
+
+			for _, userID := range userIDs {
+				key := params
+				key.userID = userID
+				byKey[key] = byUser[userID]
+			}
+		}
+		return results(keys, byKey, nil)
+	}
+
+	return &loaders{
+		subscriptions: dataloader.NewBatchedLoader(subscriptions, dataloader.WithBatchCapacity[uuid.UUID, []models.Subscription](maxBatchSize)),
+		history:       dataloader.NewBatchedLoader(history, dataloader.WithBatchCapacity[int, []models.SubscriptionPause](maxBatchSize)),
+		totals:        dataloader.NewBatchedLoader(totals, dataloader.WithBatchCapacity[totalKey, int](maxBatchSize)),
+	}
+}
+
+// results раскладывает ответ пакетного запроса по ключам в порядке keys, как требует dataloader.
+// Ключам без значения достаётся нулевое, при err — ошибка для всех.
+func results[K comparable, V any](keys []K, values map[K]V, err error) []*dataloader.Result[V] {
+	out := make([]*dataloader.Result[V], len(keys))
+	for i, key := range keys {
+		if err != nil {
+			out[i] = &dataloader.Result[V]{Error: err}
+			continue
+		}
+		out[i] = &dataloader.Result[V]{Data: values[key]}
+	}
+	return out
+}
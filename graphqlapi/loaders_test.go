@@ -0,0 +1,273 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"test/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeBatchService записывает пакеты, с которыми его вызвали загрузчики.
+type fakeBatchService struct {
+	mu            sync.Mutex
+	subscriptions [][]uuid.UUID
+	perUser       []int
+	pauses        [][]int
+	totals        []totalCall
+	err           error
+}
+
+type totalCall struct {
+	req     models.TotalCostRequest
+	userIDs []uuid.UUID
+}
+
+func (f *fakeBatchService) ListSubscriptionsByUsers(ctx context.Context, scope models.Scope, userIDs []uuid.UUID, perUser int) (map[uuid.UUID][]models.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscriptions = append(f.subscriptions, userIDs)
+	f.perUser = append(f.perUser, perUser)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// У первого пользователя подписок нет: загрузчик должен вернуть для него пустой список
+	byUser := make(map[uuid.UUID][]models.Subscription)
+	for i, userID := range userIDs[1:] {
+		byUser[userID] = []models.Subscription{{ID: i + 1, UserID: userID, OrganizationID: scope.OrganizationID}}
+	}
+	return byUser, nil
+}
+
+func (f *fakeBatchService) ListSubscriptionPauses(ctx context.Context, scope models.Scope, subscriptionIDs []int) (map[int][]models.SubscriptionPause, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pauses = append(f.pauses, subscriptionIDs)
+
+	byID := make(map[int][]models.SubscriptionPause)
+	for _, id := range subscriptionIDs {
+		byID[id] = []models.SubscriptionPause{{SubscriptionID: id, PausedAt: "2025-01-01"}}
+	}
+	return byID, nil
+}
+
+func (f *fakeBatchService) GetTotalCostByUsers(ctx context.Context, scope models.Scope, req *models.TotalCostRequest, userIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.totals = append(f.totals, totalCall{req: *req, userIDs: userIDs})
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// Итог зависит от параметров, чтобы перепутанные группы было видно
+	total := 100
+	if req.Prorate {
+		total = 50
+	}
+	if req.ServiceName != nil {
+		total += len(*req.ServiceName)
+	}
+
+	byUser := make(map[uuid.UUID]int, len(userIDs))
+	for _, userID := range userIDs {
+		byUser[userID] = total
+	}
+	return byUser, nil
+}
+
+// loadAll запускает загрузки одновременно, как это делают резолверы элементов списка, и ждёт результатов.
+func loadAll[K any, V any](keys []K, load func(K) func() (V, error)) ([]V, []error) {
+	thunks := make([]func() (V, error), len(keys))
+	for i, key := range keys {
+		thunks[i] = load(key)
+	}
+
+	values := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	for i, thunk := range thunks {
+		values[i], errs[i] = thunk()
+	}
+	return values, errs
+}
+
+func TestSubscriptionsLoaderBatches(t *testing.T) {
+	ctx := context.Background()
+	service := &fakeBatchService{}
+	scope := models.Scope{OrganizationID: "acme"}
+	l := newLoaders(service, scope)
+
+	users := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	// Повторный ключ загружается один раз
+	keys := append(users, users[1])
+
+	values, errs := loadAll(keys, func(id uuid.UUID) func() ([]models.Subscription, error) {
+		return l.subscriptions.Load(ctx, id)
+	})
+
+	if len(service.subscriptions) != 1 || len(service.subscriptions[0]) != len(users) {
+		t.Fatalf("batches = %v, want one batch of %d users", service.subscriptions, len(users))
+	}
+	if service.perUser[0] != maxUserSubscriptions {
+		t.Errorf("perUser = %d, want %d", service.perUser[0], maxUserSubscriptions)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+	}
+	if len(values[0]) != 0 {
+		t.Errorf("user without subscriptions got %v", values[0])
+	}
+	for i, key := range keys[1:] {
+		if len(values[i+1]) != 1 || values[i+1][0].UserID != key || values[i+1][0].OrganizationID != "acme" {
+			t.Errorf("key %d got %+v", i+1, values[i+1])
+		}
+	}
+
+	// Результаты кешируются до конца запроса
+	if _, err := l.subscriptions.Load(ctx, users[0])(); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.subscriptions) != 1 {
+		t.Errorf("cached key was loaded again: %d batches", len(service.subscriptions))
+	}
+}
+
+func TestHistoryLoaderBatches(t *testing.T) {
+	ctx := context.Background()
+	service := &fakeBatchService{}
+	l := newLoaders(service, models.Scope{OrganizationID: "acme"})
+
+	values, _ := loadAll([]int{3, 1, 2}, func(id int) func() ([]models.SubscriptionPause, error) {
+		return l.history.Load(ctx, id)
+	})
+
+	if len(service.pauses) != 1 {
+		t.Fatalf("batches = %v, want one", service.pauses)
+	}
+	for i, id := range []int{3, 1, 2} {
+		if len(values[i]) != 1 || values[i][0].SubscriptionID != id {
+			t.Errorf("subscription %d got %+v", id, values[i])
+		}
+	}
+}
+
+func TestLoadersLimitBatchSize(t *testing.T) {
+	ctx := context.Background()
+	service := &fakeBatchService{}
+	l := newLoaders(service, models.Scope{OrganizationID: "acme"})
+
+	ids := make([]int, maxBatchSize*2+1)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+
+	_, errs := loadAll(ids, func(id int) func() ([]models.SubscriptionPause, error) {
+		return l.history.Load(ctx, id)
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+	}
+
+	loaded := 0
+	for _, batch := range service.pauses {
+		if len(batch) > maxBatchSize {
+			t.Errorf("batch of %d keys, want at most %d", len(batch), maxBatchSize)
+		}
+		loaded += len(batch)
+	}
+	if loaded != len(ids) {
+		t.Errorf("loaded %d keys, want %d", loaded, len(ids))
+	}
+}
+
+func TestTotalsLoaderGroupsByParams(t *testing.T) {
+	ctx := context.Background()
+	service := &fakeBatchService{}
+	l := newLoaders(service, models.Scope{OrganizationID: "acme"})
+
+	first, second := uuid.New(), uuid.New()
+	keys := []totalKey{
+		{start: "2025-01-01", end: "2025-12-31", userID: first},
+		{start: "2025-01-01", end: "2025-12-31", userID: second},
+		{start: "2025-01-01", end: "2025-12-31", prorate: true, userID: first},
+		{start: "2025-01-01", end: "2025-12-31", serviceName: "Netflix", userID: second},
+	}
+	want := []int{100, 100, 50, 107}
+
+	values, errs := loadAll(keys, func(key totalKey) func() (int, error) {
+		return l.totals.Load(ctx, key)
+	})
+
+	for i := range keys {
+		if errs[i] != nil {
+			t.Fatalf("key %d: %v", i, errs[i])
+		}
+		if values[i] != want[i] {
+			t.Errorf("key %d: total = %d, want %d", i, values[i], want[i])
+		}
+	}
+
+	// Одна загрузка, три группы параметров: по запросу к сервису на группу
+	if len(service.totals) != 3 {
+		t.Fatalf("service calls = %d, want 3", len(service.totals))
+	}
+	var sizes []int
+	for _, call := range service.totals {
+		sizes = append(sizes, len(call.userIDs))
+		if call.req.ServiceName != nil && *call.req.ServiceName != "Netflix" {
+			t.Errorf("service name = %q", *call.req.ServiceName)
+		}
+	}
+	sort.Ints(sizes)
+	if sizes[0] != 1 || sizes[1] != 1 || sizes[2] != 2 {
+		t.Errorf("group sizes = %v, want [1 1 2]", sizes)
+	}
+}
+
+func TestLoadersPropagateErrors(t *testing.T) {
+	ctx := context.Background()
+	service := &fakeBatchService{err: errors.New("database is down")}
+	l := newLoaders(service, models.Scope{OrganizationID: "acme"})
+
+	users := []uuid.UUID{uuid.New(), uuid.New()}
+	_, errs := loadAll(users, func(id uuid.UUID) func() ([]models.Subscription, error) {
+		return l.subscriptions.Load(ctx, id)
+	})
+	for i, err := range errs {
+		if !errors.Is(err, service.err) {
+			t.Errorf("subscriptions key %d: error = %v", i, err)
+		}
+	}
+
+	_, errs = loadAll([]totalKey{{start: "a", userID: users[0]}, {start: "b", userID: users[1]}}, func(key totalKey) func() (int, error) {
+		return l.totals.Load(ctx, key)
+	})
+	for i, err := range errs {
+		if !errors.Is(err, service.err) {
+			t.Errorf("totals key %d: error = %v", i, err)
+		}
+	}
+}
+
+func TestResults(t *testing.T) {
+	out := results([]string{"b", "a", "c"}, map[string]int{"a": 1, "b": 2}, nil)
+	for i, want := range []int{2, 1, 0} {
+		if out[i].Error != nil || out[i].Data != want {
+			t.Errorf("result %d = %+v, want %d", i, out[i], want)
+		}
+	}
+
+	err := errors.New("failed")
+	for i, result := range results[string, int]([]string{"a", "b"}, nil, err) {
+		if result.Error != err {
+			t.Errorf("result %d error = %v", i, result.Error)
+		}
+	}
+}
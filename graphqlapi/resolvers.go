@@ -0,0 +1,490 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"test/models"
+	"test/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// resolver — корневой резолвер. Query и Mutation разделены, потому что graphql-go
+// считает метод Subscription корневого резолвера резолвером операции subscription.
+type resolver struct {
+	subscriptionService *services.SubscriptionService
+}
+
+func (r *resolver) Query() *queryResolver {
+	return &queryResolver{subscriptionService: r.subscriptionService}
+}
+
+func (r *resolver) Mutation() *mutationResolver {
+	return &mutationResolver{subscriptionService: r.subscriptionService}
+}
+
+// queryResolver и mutationResolver повторяют проверки handlers.SubscriptionHandler.
+type queryResolver struct {
+	subscriptionService *services.SubscriptionService
+}
+
+type mutationResolver struct {
+	subscriptionService *services.SubscriptionService
+}
+
+func (r *queryResolver) Subscription(ctx context.Context, args struct{ ID int32 }) (*subscriptionResolver, error) {
+	state := stateFromContext(ctx)
+
+	subscription, err := r.subscriptionService.GetSubscription(ctx, state.scope, int(args.ID))
+	if err != nil {
+		return nil, serviceError(ctx, "get subscription", err)
+	}
+
+	return &subscriptionResolver{subscription: subscription}, nil
+}
+
+type subscriptionsArgs struct {
+	Page              int32
+	Limit             int32
+	UserID            *graphql.ID
+	TrialEndingWithin *int32
+}
+
+func (r *queryResolver) Subscriptions(ctx context.Context, args subscriptionsArgs) (*subscriptionListResolver, error) {
+	state := stateFromContext(ctx)
+
+	filter := models.ListSubscriptionsFilter{Page: int(args.Page), Limit: int(args.Limit)}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 10
+	}
+
+	if args.UserID != nil {
+		userID, err := parseUserID(*args.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter.UserID = &userID
+	}
+
+	if args.TrialEndingWithin != nil {
+		if *args.TrialEndingWithin < 0 {
+			return nil, badRequest("trialEndingWithin must be a non-negative integer")
+		}
+		trialEndingWithin := int(*args.TrialEndingWithin)
+		filter.TrialEndingWithin = &trialEndingWithin
+	}
+
+	data, err := r.subscriptionService.ListSubscriptions(ctx, state.scope, filter)
+	if err != nil {
+		return nil, serviceError(ctx, "list subscriptions", err)
+	}
+
+	return &subscriptionListResolver{subscriptions: subscriptionResolvers(data.Subscriptions), total: Int64(data.Total)}, nil
+}
+
+func (r *queryResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	users, err := r.Users(ctx, struct{ IDs []graphql.ID }{IDs: []graphql.ID{args.ID}})
+	if err != nil {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+func (r *queryResolver) Users(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*userResolver, error) {
+	if len(args.IDs) > maxUsers {
+		return nil, badRequest(fmt.Sprintf("ids must contain at most %d users", maxUsers))
+	}
+
+	state := stateFromContext(ctx)
+
+	users := make([]*userResolver, 0, len(args.IDs))
+	for _, id := range args.IDs {
+		userID, err := parseUserID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := state.scope.Restrict(&userID); err != nil {
+			return nil, serviceError(ctx, "get user", err)
+		}
+
+		users = append(users, &userResolver{id: userID})
+	}
+
+	return users, nil
+}
+
+type totalArgs struct {
+	Start       string
+	End         string
+	UserID      *graphql.ID
+	ServiceName *string
+	Prorate     bool
+}
+
+func (r *queryResolver) Total(ctx context.Context, args totalArgs) (Int64, error) {
+	state := stateFromContext(ctx)
+
+	request := models.TotalCostRequest{
+		PeriodStart: args.Start,
+		PeriodEnd:   args.End,
+		ServiceName: args.ServiceName,
+		Prorate:     args.Prorate,
+	}
+	if args.UserID != nil {
+		userID, err := parseUserID(*args.UserID)
+		if err != nil {
+			return 0, err
+		}
+		request.UserID = &userID
+	}
+
+	if err := request.Validate(); err != nil {
+		return 0, badRequest(err.Error())
+	}
+
+	total, err := r.subscriptionService.GetTotalCost(ctx, state.scope, &request)
+	if err != nil {
+		return 0, serviceError(ctx, "get total cost", err)
+	}
+
+	return Int64(total.Total), nil
+}
+
+type createSubscriptionInput struct {
+	ServiceName  string
+	Price        int32
+	UserID       *graphql.ID
+	StartDate    string
+	EndDate      *string
+	TrialDays    *int32
+	TrialEndDate *string
+}
+
+func (r *mutationResolver) CreateSubscription(ctx context.Context, args struct{ Input createSubscriptionInput }) (*subscriptionResolver, error) {
+	state, err := mutationState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := args.Input
+
+	var userID uuid.UUID
+	if input.UserID != nil {
+		userID, err = parseUserID(*input.UserID)
+		if err != nil {
+			return nil, err
+		}
+	} else if state.scope.UserID != nil {
+		userID = *state.scope.UserID
+	}
+
+	subscription := &models.Subscription{
+		ServiceName:  input.ServiceName,
+		Price:        int(input.Price),
+		UserID:       userID,
+		StartDate:    input.StartDate,
+		EndDate:      input.EndDate,
+		TrialEndDate: input.TrialEndDate,
+		Status:       models.StatusActive,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	if input.TrialDays != nil {
+		if input.TrialEndDate != nil {
+			return nil, badRequest("trialDays and trialEndDate are mutually exclusive")
+		}
+
+		if err := subscription.ApplyTrialDays(int(*input.TrialDays)); err != nil {
+			return nil, badRequest(err.Error())
+		}
+	}
+
+	if err := r.subscriptionService.CreateSubscription(ctx, state.scope, subscription); err != nil {
+		return nil, serviceError(ctx, "create subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription created",
+		"id", subscription.ID, "user_id", subscription.UserID, "service", subscription.ServiceName, "price", subscription.Price)
+
+	return &subscriptionResolver{subscription: *subscription}, nil
+}
+
+type updateSubscriptionInput struct {
+	ServiceName  *string
+	Price        *int32
+	StartDate    *string
+	EndDate      *string
+	TrialEndDate *string
+}
+
+func (r *mutationResolver) UpdateSubscription(ctx context.Context, args struct {
+	ID    int32
+	Input updateSubscriptionInput
+}) (*subscriptionResolver, error) {
+	state, err := mutationState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := models.UpdateSubscriptionRequest{
+		ServiceName:  args.Input.ServiceName,
+		StartDate:    args.Input.StartDate,
+		EndDate:      args.Input.EndDate,
+		TrialEndDate: args.Input.TrialEndDate,
+	}
+	if args.Input.Price != nil {
+		price := int(*args.Input.Price)
+		request.Price = &price
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	subscription, err := r.subscriptionService.UpdateSubscription(ctx, state.scope, int(args.ID), request)
+	if err != nil {
+		return nil, serviceError(ctx, "update subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription updated", "id", args.ID)
+
+	return &subscriptionResolver{subscription: subscription}, nil
+}
+
+func (r *mutationResolver) DeleteSubscription(ctx context.Context, args struct{ ID int32 }) (bool, error) {
+	state, err := mutationState(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.subscriptionService.DeleteSubscription(ctx, state.scope, int(args.ID)); err != nil {
+		return false, serviceError(ctx, "delete subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription deleted", "id", args.ID)
+
+	return true, nil
+}
+
+func (r *mutationResolver) PauseSubscription(ctx context.Context, args struct{ ID int32 }) (*subscriptionResolver, error) {
+	return r.changeStatus(ctx, "pause", int(args.ID), r.subscriptionService.PauseSubscription)
+}
+
+func (r *mutationResolver) ResumeSubscription(ctx context.Context, args struct{ ID int32 }) (*subscriptionResolver, error) {
+	return r.changeStatus(ctx, "resume", int(args.ID), r.subscriptionService.ResumeSubscription)
+}
+
+func (r *mutationResolver) CancelSubscription(ctx context.Context, args struct{ ID int32 }) (*subscriptionResolver, error) {
+	return r.changeStatus(ctx, "cancel", int(args.ID), r.subscriptionService.CancelSubscription)
+}
+
+func (r *mutationResolver) changeStatus(ctx context.Context, action string, id int, change func(context.Context, models.Scope, int) (models.Subscription, error)) (*subscriptionResolver, error) {
+	state, err := mutationState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := change(ctx, state.scope, id)
+	if err != nil {
+		return nil, serviceError(ctx, action+" subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription status changed", "action", action, "id", id, "status", subscription.Status)
+
+	return &subscriptionResolver{subscription: subscription}, nil
+}
+
+type subscriptionListResolver struct {
+	subscriptions []*subscriptionResolver
+	total         Int64
+}
+
+func (r *subscriptionListResolver) Subscriptions() []*subscriptionResolver {
+	return r.subscriptions
+}
+
+func (r *subscriptionListResolver) Total() Int64 {
+	return r.total
+}
+
+type userResolver struct {
+	id uuid.UUID
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.id.String())
+}
+
+func (r *userResolver) Subscriptions(ctx context.Context) ([]*subscriptionResolver, error) {
+	subscriptions, err := stateFromContext(ctx).loaders.subscriptions.Load(ctx, r.id)()
+	if err != nil {
+		return nil, serviceError(ctx, "list subscriptions", err)
+	}
+	return subscriptionResolvers(subscriptions), nil
+}
+
+func (r *userResolver) Total(ctx context.Context, args struct {
+	Start       string
+	End         string
+	ServiceName *string
+	Prorate     bool
+}) (Int64, error) {
+	request := models.TotalCostRequest{PeriodStart: args.Start, PeriodEnd: args.End, ServiceName: args.ServiceName}
+	if err := request.Validate(); err != nil {
+		return 0, badRequest(err.Error())
+	}
+
+	key := totalKey{start: request.PeriodStart, end: request.PeriodEnd, prorate: args.Prorate, userID: r.id}
+	if args.ServiceName != nil {
+		key.serviceName = *args.ServiceName
+	}
+
+	total, err := stateFromContext(ctx).loaders.totals.Load(ctx, key)()
+	if err != nil {
+		return 0, serviceError(ctx, "get total cost", err)
+	}
+	return Int64(total), nil
+}
+
+type subscriptionResolver struct {
+	subscription models.Subscription
+}
+
+func subscriptionResolvers(subscriptions []models.Subscription) []*subscriptionResolver {
+	resolvers := make([]*subscriptionResolver, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resolvers = append(resolvers, &subscriptionResolver{subscription: subscription})
+	}
+	return resolvers
+}
+
+func (r *subscriptionResolver) ID() (int32, error) {
+	return toInt32("id", r.subscription.ID)
+}
+
+func (r *subscriptionResolver) OrganizationID() string {
+	return r.subscription.OrganizationID
+}
+
+func (r *subscriptionResolver) ServiceName() string {
+	return r.subscription.ServiceName
+}
+
+func (r *subscriptionResolver) Price() (int32, error) {
+	return toInt32("price", r.subscription.Price)
+}
+
+func (r *subscriptionResolver) UserID() graphql.ID {
+	return graphql.ID(r.subscription.UserID.String())
+}
+
+func (r *subscriptionResolver) User() *userResolver {
+	return &userResolver{id: r.subscription.UserID}
+}
+
+func (r *subscriptionResolver) StartDate() string {
+	return r.subscription.StartDate
+}
+
+func (r *subscriptionResolver) EndDate() *string {
+	return r.subscription.EndDate
+}
+
+func (r *subscriptionResolver) TrialEndDate() *string {
+	return r.subscription.TrialEndDate
+}
+
+func (r *subscriptionResolver) Status() string {
+	return string(r.subscription.Status)
+}
+
+func (r *subscriptionResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.subscription.CreatedAt}
+}
+
+func (r *subscriptionResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.subscription.UpdatedAt}
+}
+
+func (r *subscriptionResolver) History(ctx context.Context) ([]*pauseResolver, error) {
+	pauses, err := stateFromContext(ctx).loaders.history.Load(ctx, r.subscription.ID)()
+	if err != nil {
+		return nil, serviceError(ctx, "list subscription history", err)
+	}
+
+	resolvers := make([]*pauseResolver, 0, len(pauses))
+	for _, pause := range pauses {
+		resolvers = append(resolvers, &pauseResolver{pause: pause})
+	}
+	return resolvers, nil
+}
+
+type pauseResolver struct {
+	pause models.SubscriptionPause
+}
+
+func (r *pauseResolver) PausedAt() string {
+	return r.pause.PausedAt
+}
+
+func (r *pauseResolver) ResumedAt() *string {
+	return r.pause.ResumedAt
+}
+
+func parseUserID(id graphql.ID) (uuid.UUID, error) {
+	userID, err := uuid.Parse(string(id))
+	if err != nil {
+		return uuid.Nil, badRequest("user id must be a valid UUID")
+	}
+	return userID, nil
+}
+
+// apiError — ошибка резолвера. code попадает в extensions.code и соответствует HTTP-коду,
+// который вернул бы аналогичный REST-запрос.
+type apiError struct {
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func (e *apiError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func badRequest(message string) *apiError {
+	return &apiError{code: "BAD_REQUEST", message: message}
+}
+
+// serviceError переводит ошибку сервиса в apiError так же, как REST-обработчики — в HTTP-код.
+func serviceError(ctx context.Context, operation string, err error) error {
+	switch {
+	case errors.Is(err, models.ErrForbidden):
+		return &apiError{code: "FORBIDDEN", message: "access to other users' subscriptions is forbidden"}
+	case errors.Is(err, models.ErrInvalidDates):
+		return badRequest(err.Error())
+	case errors.Is(err, models.ErrInvalidStatusTransition):
+		return &apiError{code: "CONFLICT", message: err.Error()}
+	case err.Error() == "subscription not found":
+		return &apiError{code: "NOT_FOUND", message: "subscription not found"}
+	}
+
+	// Текст ошибки БД клиенту не отдаётся, как и в gRPC
+	slog.ErrorContext(ctx, "failed to "+operation, "error", err)
+	return &apiError{code: "INTERNAL", message: "failed to " + operation}
+}
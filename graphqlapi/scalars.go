@@ -0,0 +1,51 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Int64 — скаляр для сумм и счётчиков, которые не помещаются во встроенный 32-битный Int.
+type Int64 int64
+
+func (Int64) ImplementsGraphQLType(name string) bool {
+	return name == "Int64"
+}
+
+// UnmarshalGraphQL принимает число или строку с числом: клиенты на JavaScript теряют точность после 2^53.
+func (i *Int64) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		*i = Int64(v)
+	case int64:
+		*i = Int64(v)
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return fmt.Errorf("invalid Int64: %v", v)
+		}
+		*i = Int64(v)
+	case string:
+		value, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Int64: %q", v)
+		}
+		*i = Int64(value)
+	default:
+		return fmt.Errorf("invalid Int64 type: %T", input)
+	}
+	return nil
+}
+
+func (i Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(i))
+}
+
+// toInt32 возвращает значение для поля Int или ошибку, если оно не помещается в 32 бита.
+func toInt32(field string, value int) (int32, error) {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, &apiError{code: "INTERNAL", message: field + " does not fit into Int"}
+	}
+	return int32(value), nil
+}
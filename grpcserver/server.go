@@ -0,0 +1,84 @@
+//go:generate protoc -I ../proto --go_out=../gen --go_opt=paths=source_relative --go-grpc_out=../gen --go-grpc_opt=paths=source_relative subscriptions/v1/subscriptions.proto
+
+// Package grpcserver отдаёт операции с подписками по gRPC на отдельном порту.
+package grpcserver
+
+import (
+	"context"
+	"net"
+	subscriptionsv1 "test/gen/subscriptions/v1"
+	"test/logging"
+	"test/services"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// ReadMethods — методы только на чтение; API-ключу для них достаточно scope read.
+var ReadMethods = []string{
+	subscriptionsv1.SubscriptionService_GetSubscription_FullMethodName,
+	subscriptionsv1.SubscriptionService_ListSubscriptions_FullMethodName,
+	subscriptionsv1.SubscriptionService_GetTotalCost_FullMethodName,
+}
+
+// PublicMethods доступны без аутентификации: health-проверки оркестратора.
+// Reflection — потоковый сервис, unary-интерсепторы к нему не применяются, поэтому он выключен по умолчанию.
+var PublicMethods = []string{"/grpc.health.v1.Health/*"}
+
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+// New создаёт сервер с SubscriptionService и health, а при withReflection — и с reflection.
+// interceptors выполняются после трассировки и логирования, например auth.UnaryServerInterceptor.
+func New(subscriptionService *services.SubscriptionService, withReflection bool, interceptors ...grpc.UnaryServerInterceptor) *Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor()}, interceptors...)...),
+	)
+
+	subscriptionsv1.RegisterSubscriptionServiceServer(server, &subscriptionServer{subscriptionService: subscriptionService})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if withReflection {
+		reflection.Register(server)
+	}
+
+	return &Server{grpc: server, health: healthServer}
+}
+
+// Serve слушает address и блокируется до остановки сервера.
+func (s *Server) Serve(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown переводит health в NOT_SERVING и дожидается текущих вызовов, но не дольше ctx,
+// после чего обрывает оставшиеся.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,286 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"test/auth"
+	subscriptionsv1 "test/gen/subscriptions/v1"
+	"test/models"
+	"test/services"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriptionServer повторяет проверки handlers.SubscriptionHandler и вызывает тот же SubscriptionService.
+type subscriptionServer struct {
+	subscriptionsv1.UnimplementedSubscriptionServiceServer
+	subscriptionService *services.SubscriptionService
+}
+
+func (s *subscriptionServer) CreateSubscription(ctx context.Context, req *subscriptionsv1.CreateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var userID uuid.UUID
+	if req.UserId != "" {
+		userID, err = uuid.Parse(req.UserId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "user_id must be a valid UUID")
+		}
+	} else if scope.UserID != nil {
+		userID = *scope.UserID
+	}
+
+	price, err := int32Field("price", req.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.Subscription{
+		ServiceName:  req.ServiceName,
+		Price:        price,
+		UserID:       userID,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		TrialEndDate: req.TrialEndDate,
+		Status:       models.StatusActive,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.TrialDays != nil {
+		if req.TrialEndDate != nil {
+			return nil, status.Error(codes.InvalidArgument, "trial_days and trial_end_date are mutually exclusive")
+		}
+
+		if err := subscription.ApplyTrialDays(int(*req.TrialDays)); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if err := s.subscriptionService.CreateSubscription(ctx, scope, subscription); err != nil {
+		return nil, statusError(ctx, "create subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription created",
+		"id", subscription.ID, "user_id", subscription.UserID, "service", subscription.ServiceName, "price", subscription.Price)
+
+	return toProto(*subscription), nil
+}
+
+func (s *subscriptionServer) GetSubscription(ctx context.Context, req *subscriptionsv1.GetSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := int32Field("id", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.subscriptionService.GetSubscription(ctx, scope, id)
+	if err != nil {
+		return nil, statusError(ctx, "get subscription", err)
+	}
+
+	return toProto(subscription), nil
+}
+
+func (s *subscriptionServer) UpdateSubscription(ctx context.Context, req *subscriptionsv1.UpdateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := int32Field("id", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	request := models.UpdateSubscriptionRequest{
+		ServiceName:  req.ServiceName,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		TrialEndDate: req.TrialEndDate,
+	}
+	if req.Price != nil {
+		price, err := int32Field("price", *req.Price)
+		if err != nil {
+			return nil, err
+		}
+		request.Price = &price
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	subscription, err := s.subscriptionService.UpdateSubscription(ctx, scope, id, request)
+	if err != nil {
+		return nil, statusError(ctx, "update subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription updated", "id", req.Id)
+
+	return toProto(subscription), nil
+}
+
+func (s *subscriptionServer) DeleteSubscription(ctx context.Context, req *subscriptionsv1.DeleteSubscriptionRequest) (*subscriptionsv1.DeleteSubscriptionResponse, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := int32Field("id", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptionService.DeleteSubscription(ctx, scope, id); err != nil {
+		return nil, statusError(ctx, "delete subscription", err)
+	}
+
+	slog.InfoContext(ctx, "subscription deleted", "id", req.Id)
+
+	return &subscriptionsv1.DeleteSubscriptionResponse{}, nil
+}
+
+func (s *subscriptionServer) ListSubscriptions(ctx context.Context, req *subscriptionsv1.ListSubscriptionsRequest) (*subscriptionsv1.ListSubscriptionsResponse, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.ListSubscriptionsFilter{Page: int(req.Page), Limit: int(req.Limit)}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 10
+	}
+
+	if req.UserId != nil {
+		userID, err := uuid.Parse(*req.UserId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "user_id must be a valid UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	if req.TrialEndingWithin != nil {
+		if *req.TrialEndingWithin < 0 {
+			return nil, status.Error(codes.InvalidArgument, "trial_ending_within must be a non-negative integer")
+		}
+		trialEndingWithin := int(*req.TrialEndingWithin)
+		filter.TrialEndingWithin = &trialEndingWithin
+	}
+
+	data, err := s.subscriptionService.ListSubscriptions(ctx, scope, filter)
+	if err != nil {
+		return nil, statusError(ctx, "list subscriptions", err)
+	}
+
+	response := &subscriptionsv1.ListSubscriptionsResponse{
+		Subscriptions: make([]*subscriptionsv1.Subscription, 0, len(data.Subscriptions)),
+		Total:         int64(data.Total),
+	}
+	for _, subscription := range data.Subscriptions {
+		response.Subscriptions = append(response.Subscriptions, toProto(subscription))
+	}
+
+	return response, nil
+}
+
+func (s *subscriptionServer) GetTotalCost(ctx context.Context, req *subscriptionsv1.GetTotalCostRequest) (*subscriptionsv1.GetTotalCostResponse, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := models.TotalCostRequest{
+		PeriodStart: req.Start,
+		PeriodEnd:   req.End,
+		ServiceName: req.ServiceName,
+		Prorate:     req.Prorate,
+	}
+	if req.UserId != nil {
+		userID, err := uuid.Parse(*req.UserId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "user_id must be a valid UUID")
+		}
+		request.UserID = &userID
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	total, err := s.subscriptionService.GetTotalCost(ctx, scope, &request)
+	if err != nil {
+		return nil, statusError(ctx, "get total cost", err)
+	}
+
+	return &subscriptionsv1.GetTotalCostResponse{Total: int64(total.Total)}, nil
+}
+
+// int32Field возвращает значение поля или InvalidArgument, если оно не помещается в INTEGER-колонку БД.
+func int32Field(field string, value int64) (int, error) {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, status.Error(codes.InvalidArgument, field+" is out of range")
+	}
+	return int(value), nil
+}
+
+func scopeFromContext(ctx context.Context) (models.Scope, error) {
+	claims, _ := auth.ClaimsFromContext(ctx)
+	scope, err := auth.ScopeFromClaims(claims)
+	if err != nil {
+		return models.Scope{}, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return scope, nil
+}
+
+// statusError переводит ошибку сервиса в gRPC-статус так же, как REST-обработчики — в HTTP-код.
+func statusError(ctx context.Context, operation string, err error) error {
+	switch {
+	case errors.Is(err, models.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "access to other users' subscriptions is forbidden")
+	case errors.Is(err, models.ErrInvalidDates):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case err.Error() == "subscription not found":
+		return status.Error(codes.NotFound, "subscription not found")
+	}
+
+	// Текст ошибки БД клиенту не отдаётся, он есть в логе вместе с trace_id
+	slog.ErrorContext(ctx, "failed to "+operation, "error", err)
+	return status.Error(codes.Internal, "failed to "+operation)
+}
+
+func toProto(subscription models.Subscription) *subscriptionsv1.Subscription {
+	return &subscriptionsv1.Subscription{
+		Id:             int64(subscription.ID),
+		OrganizationId: subscription.OrganizationID,
+		ServiceName:    subscription.ServiceName,
+		Price:          int64(subscription.Price),
+		UserId:         subscription.UserID.String(),
+		StartDate:      subscription.StartDate,
+		EndDate:        subscription.EndDate,
+		TrialEndDate:   subscription.TrialEndDate,
+		Status:         string(subscription.Status),
+		CreatedAt:      timestamppb.New(subscription.CreatedAt),
+		UpdatedAt:      timestamppb.New(subscription.UpdatedAt),
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log/slog"
+	"test/models"
+	"test/scheduler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewAdminHandler(scheduler *scheduler.Scheduler) *AdminHandler {
+	return &AdminHandler{scheduler: scheduler}
+}
+
+// ListJobs возвращает состояние фоновых задач
+// @Summary      Состояние фоновых задач
+// @Description  Время и результат последнего запуска общие для всех реплик, running относится к текущей.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  models.JobsResponse  "Успеx"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/admin/jobs [get]
+func (h *AdminHandler) ListJobs(c *fiber.Ctx) error {
+	jobs, err := h.scheduler.Status(c.UserContext())
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to get jobs", "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to get jobs: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.JobsResponse{
+		Status:  true,
+		Message: "success",
+		Data:    jobs,
+	})
+}
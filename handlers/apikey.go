@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"log/slog"
+	"test/auth"
+	"test/models"
+	"test/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey выпускает API-ключ
+// @Summary      Выпустить API-ключ
+// @Description  Ключ возвращается только в этом ответе, в БД хранится его SHA-256.
+// @Description  read разрешает GET, write — изменяющие запросы, admin — всё, включая администрирование.
+// @Description  Ключ с user_id действует от имени этого пользователя, без него — по всем пользователям.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        body  body  models.CreateAPIKeyRequest  true  "Тело запроса"
+// @Success      200  {object}  models.CreatedAPIKeyResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный запрос"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/api-keys/ [post]
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	var request models.CreateAPIKeyRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if err := request.Validate(); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(c.UserContext(), scope.OrganizationID, request)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to create api key", "name", request.Name, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to create api key: " + err.Error(),
+		})
+	}
+
+	slog.InfoContext(c.UserContext(), "api key created", "id", key.ID, "prefix", key.Prefix, "scopes", key.Scopes)
+
+	return c.JSON(models.CreatedAPIKeyResponse{
+		Status:  true,
+		Message: "success",
+		Data:    key,
+	})
+}
+
+// ListAPIKeys возвращает выпущенные API-ключи без секретов
+// @Summary      Список API-ключей
+// @Tags         api-keys
+// @Produce      json
+// @Success      200  {object}  models.APIKeysResponse  "Успеx"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/api-keys/ [get]
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.UserContext(), scope.OrganizationID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to list api keys", "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to list api keys: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.APIKeysResponse{
+		Status:  true,
+		Message: "success",
+		Data:    keys,
+	})
+}
+
+// RevokeAPIKey отзывает API-ключ
+// @Summary      Отозвать API-ключ
+// @Tags         api-keys
+// @Produce      json
+// @Param        id   path      int  true  "ID ключа"
+// @Success      200  {object}  models.SuccessResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      404  {object}  models.ErrorResponse  "Ключ не найден"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.UserContext(), scope.OrganizationID, id); err != nil {
+		if err.Error() == "api key not found" {
+			return c.Status(404).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "api key not found",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to revoke api key", "id", id, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to revoke api key: " + err.Error(),
+		})
+	}
+
+	slog.InfoContext(c.UserContext(), "api key revoked", "id", id)
+
+	return c.JSON(models.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"strings"
+	"test/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReadConsistencyHeader — заголовок, которым клиент просит читать из primary сразу после своей записи.
+const ReadConsistencyHeader = "X-Read-Consistency"
+
+// ReadYourWrites направляет чтения запроса в primary, если передан X-Read-Consistency: strong.
+// Без заголовка списки и отчёты читаются с реплики и могут отставать на время репликации.
+func ReadYourWrites() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if strings.EqualFold(c.Get(ReadConsistencyHeader), "strong") {
+			c.SetUserContext(db.WithPrimaryReads(c.UserContext()))
+		}
+		return c.Next()
+	}
+}
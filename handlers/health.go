@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"test/db"
+	"test/models"
+	"test/scheduler"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	db        *db.DB
+	scheduler *scheduler.Scheduler
+}
+
+func NewHealthHandler(db *db.DB, scheduler *scheduler.Scheduler) *HealthHandler {
+	return &HealthHandler{db: db, scheduler: scheduler}
+}
+
+// Liveness сообщает, что процесс жив
+// @Summary      Liveness-проба
+// @Description  Не обращается к зависимостям: отвечает 200, пока процесс обрабатывает запросы.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  models.HealthResponse  "Процесс жив"
+// @Router       /healthz [get]
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.JSON(models.HealthResponse{Status: models.HealthOK})
+}
+
+// Readiness проверяет, готов ли экземпляр принимать трафик
+// @Summary      Readiness-проба
+// @Description  Проверяет соединение с БД, что схема на ожидаемой версии миграций и что фоновые задачи не зависли.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  models.HealthResponse  "Готов"
+// @Failure      503  {object}  models.HealthResponse  "Не готов"
+// @Router       /readyz [get]
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]models.HealthCheck{
+		"database":   h.checkDatabase(ctx),
+		"migrations": h.checkMigrations(ctx),
+		"jobs":       h.checkJobs(),
+	}
+
+	response := models.HealthResponse{Status: models.HealthOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != models.HealthOK {
+			response.Status = models.HealthUnavailable
+			return c.Status(503).JSON(response)
+		}
+	}
+
+	return c.JSON(response)
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) models.HealthCheck {
+	if err := h.db.Ping(ctx); err != nil {
+		return models.HealthCheck{Status: models.HealthUnavailable, Error: err.Error()}
+	}
+
+	stats := h.db.GetDB().Stats()
+	return models.HealthCheck{
+		Status: models.HealthOK,
+		Details: fiber.Map{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		},
+	}
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) models.HealthCheck {
+	status, err := h.db.MigrationStatus(ctx)
+	if err != nil {
+		return models.HealthCheck{Status: models.HealthUnavailable, Error: err.Error()}
+	}
+
+	check := models.HealthCheck{Status: models.HealthOK, Details: status}
+	switch {
+	case status.Dirty:
+		check.Status = models.HealthUnavailable
+		check.Error = fmt.Sprintf("migration %d is dirty", status.Version)
+	case status.Version != status.Expected:
+		check.Status = models.HealthUnavailable
+		check.Error = fmt.Sprintf("schema version %d, expected %d", status.Version, status.Expected)
+	}
+	return check
+}
+
+func (h *HealthHandler) checkJobs() models.HealthCheck {
+	stalled := h.scheduler.Stalled()
+	if len(stalled) > 0 {
+		return models.HealthCheck{
+			Status:  models.HealthUnavailable,
+			Error:   "background jobs are stalled",
+			Details: fiber.Map{"stalled": stalled},
+		}
+	}
+
+	return models.HealthCheck{Status: models.HealthOK}
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"test/auth"
 	"test/models"
 	"test/services"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type SubscriptionHandler struct {
@@ -25,9 +29,20 @@ func NewSubscriptionHandler(subscriptionService *services.SubscriptionService) *
 // @Param        body  body  models.CreateSubscriptionRequest  true  "Тело запроса"
 // @Success      200  {object}  models.SubscriptionResponse  "Успеx"
 // @Failure      400  {object}  models.ErrorResponse  "Невалидный запрос"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/ [post]
 func (h *SubscriptionHandler) CreateSubscription(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	var request models.CreateSubscriptionRequest
 
 	if err := c.BodyParser(&request); err != nil {
@@ -37,14 +52,20 @@ func (h *SubscriptionHandler) CreateSubscription(c *fiber.Ctx) error {
 		})
 	}
 
+	if request.UserID == uuid.Nil && scope.UserID != nil {
+		request.UserID = *scope.UserID
+	}
+
 	subscription := &models.Subscription{
-		ServiceName: request.ServiceName,
-		Price:       request.Price,
-		UserID:      request.UserID,
-		StartDate:   request.StartDate,
-		EndDate:     request.EndDate,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ServiceName:  request.ServiceName,
+		Price:        request.Price,
+		UserID:       request.UserID,
+		StartDate:    request.StartDate,
+		EndDate:      request.EndDate,
+		TrialEndDate: request.TrialEndDate,
+		Status:       models.StatusActive,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
 	if err := subscription.Validate(); err != nil {
@@ -54,17 +75,39 @@ func (h *SubscriptionHandler) CreateSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.subscriptionService.CreateSubscription(subscription)
+	if request.TrialDays != nil {
+		if request.TrialEndDate != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "trial_days and trial_end_date are mutually exclusive",
+			})
+		}
+
+		if err := subscription.ApplyTrialDays(*request.TrialDays); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	err = h.subscriptionService.CreateSubscription(c.UserContext(), scope, subscription)
 	if err != nil {
-		log.Printf("[ERROR CREATE] User=%s Error=%v", request.UserID, err)
+		if errors.Is(err, models.ErrForbidden) {
+			return c.Status(403).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "access to other users' subscriptions is forbidden",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to create subscription", "user_id", request.UserID, "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to create subscription: " + err.Error(),
 		})
 	}
 
-	log.Printf("[CREATE] ID=%d User=%s Service=%s Price=%d",
-		subscription.ID, subscription.UserID, subscription.ServiceName, subscription.Price)
+	slog.InfoContext(c.UserContext(), "subscription created",
+		"id", subscription.ID, "user_id", subscription.UserID, "service", subscription.ServiceName, "price", subscription.Price)
 
 	return c.JSON(models.SubscriptionResponse{
 		Status:  true,
@@ -81,9 +124,20 @@ func (h *SubscriptionHandler) CreateSubscription(c *fiber.Ctx) error {
 // @Success      200  {object}  models.SubscriptionResponse  "Успеx"
 // @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
 // @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/{id} [get]
 func (h *SubscriptionHandler) GetSubscription(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{
@@ -92,23 +146,23 @@ func (h *SubscriptionHandler) GetSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	subscription, err := h.subscriptionService.GetSubscription(id)
+	subscription, err := h.subscriptionService.GetSubscription(c.UserContext(), scope, id)
 	if err != nil {
 		if err.Error() == "subscription not found" {
-			log.Printf("[GET] Subscription not found: ID=%d", id)
+			slog.InfoContext(c.UserContext(), "subscription not found", "id", id)
 			return c.Status(404).JSON(models.ErrorResponse{
 				Status:  false,
 				Message: "subscription not found",
 			})
 		}
-		log.Printf("[ERROR GET] ID=%d Error=%v", id, err)
+		slog.ErrorContext(c.UserContext(), "failed to get subscription", "id", id, "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to get subscription: " + err.Error(),
 		})
 	}
 
-	log.Printf("[GET] ID=%d", id)
+	slog.DebugContext(c.UserContext(), "subscription fetched", "id", id)
 
 	return c.JSON(models.SubscriptionResponse{
 		Status:  true,
@@ -125,9 +179,20 @@ func (h *SubscriptionHandler) GetSubscription(c *fiber.Ctx) error {
 // @Success      200  {object}  models.SuccessResponse  "Успеx"
 // @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
 // @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/{id} [delete]
 func (h *SubscriptionHandler) DeleteSubscription(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{
@@ -136,21 +201,21 @@ func (h *SubscriptionHandler) DeleteSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.subscriptionService.DeleteSubscription(id); err != nil {
+	if err := h.subscriptionService.DeleteSubscription(c.UserContext(), scope, id); err != nil {
 		if err.Error() == "subscription not found" {
 			return c.Status(404).JSON(models.ErrorResponse{
 				Status:  false,
 				Message: "subscription not found",
 			})
 		}
-		log.Printf("[ERROR DELETE] ID=%d Error=%v", id, err)
+		slog.ErrorContext(c.UserContext(), "failed to delete subscription", "id", id, "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to delete subscription: " + err.Error(),
 		})
 	}
 
-	log.Printf("[DELETE] ID=%d", id)
+	slog.InfoContext(c.UserContext(), "subscription deleted", "id", id)
 
 	return c.JSON(models.SuccessResponse{
 		Status:  true,
@@ -164,10 +229,25 @@ func (h *SubscriptionHandler) DeleteSubscription(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        page   query  int  false  "Страница"   default(1)
 // @Param        limit  query  int  false  "Лимит"      default(10)
+// @Param        user_id  query  string  false  "Фильтр по UUID пользователя"
+// @Param        trial_ending_within  query  int  false  "Только подписки, у которых пробный период заканчивается в ближайшие N дней"
+// @Param        X-Read-Consistency  header  string  false  "strong — читать из primary, а не из реплики"
 // @Success      200  {object}  models.ListResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидные параметры"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/list [get]
 func (h *SubscriptionHandler) ListSubscriptions(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 10)
 
@@ -178,17 +258,47 @@ func (h *SubscriptionHandler) ListSubscriptions(c *fiber.Ctx) error {
 		limit = 10
 	}
 
-	data, err := h.subscriptionService.ListSubscriptions(page, limit)
+	filter := models.ListSubscriptionsFilter{Page: page, Limit: limit}
+
+	if c.Query("user_id") != "" {
+		userID, err := uuid.Parse(c.Query("user_id"))
+		if err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "user_id must be a valid UUID",
+			})
+		}
+		filter.UserID = &userID
+	}
+
+	if c.Query("trial_ending_within") != "" {
+		trialEndingWithin := c.QueryInt("trial_ending_within", -1)
+		if trialEndingWithin < 0 {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "trial_ending_within must be a non-negative integer",
+			})
+		}
+		filter.TrialEndingWithin = &trialEndingWithin
+	}
+
+	data, err := h.subscriptionService.ListSubscriptions(c.UserContext(), scope, filter)
 	if err != nil {
-		log.Printf("[ERROR LIST] Page=%d Limit=%d Error=%v", page, limit, err)
+		if errors.Is(err, models.ErrForbidden) {
+			return c.Status(403).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "access to other users' subscriptions is forbidden",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to list subscriptions", "page", page, "limit", limit, "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to list subscriptions: " + err.Error(),
 		})
 	}
 
-	log.Printf("[LIST] Page=%d Limit=%d Count=%d Total=%d",
-		page, limit, len(data.Subscriptions), data.Total)
+	slog.DebugContext(c.UserContext(), "subscriptions listed",
+		"page", page, "limit", limit, "count", len(data.Subscriptions), "total", data.Total)
 
 	return c.JSON(models.ListResponse{
 		Status:  true,
@@ -207,9 +317,20 @@ func (h *SubscriptionHandler) ListSubscriptions(c *fiber.Ctx) error {
 // @Success      200  {object}  models.SubscriptionResponse  "Успеx"
 // @Failure      400  {object}  models.ErrorResponse  "Невалидный запрос"
 // @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/{id} [put]
 func (h *SubscriptionHandler) UpdateSubscription(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{
@@ -227,22 +348,35 @@ func (h *SubscriptionHandler) UpdateSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	data, err := h.subscriptionService.UpdateSubscription(id, request)
+	if err := request.Validate(); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	data, err := h.subscriptionService.UpdateSubscription(c.UserContext(), scope, id, request)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidDates) {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: err.Error(),
+			})
+		}
 		if err.Error() == "subscription not found" {
 			return c.Status(404).JSON(models.ErrorResponse{
 				Status:  false,
 				Message: "subscription not found",
 			})
 		}
-		log.Printf("[ERROR UPDATE] ID=%d Error=%v", id, err)
+		slog.ErrorContext(c.UserContext(), "failed to update subscription", "id", id, "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to update subscription: " + err.Error(),
 		})
 	}
 
-	log.Printf("[UPDATE] ID=%d", id)
+	slog.InfoContext(c.UserContext(), "subscription updated", "id", id)
 
 	return c.JSON(models.SubscriptionResponse{
 		Status:  true,
@@ -255,17 +389,30 @@ func (h *SubscriptionHandler) UpdateSubscription(c *fiber.Ctx) error {
 // @Summary      Суммарная стоимость за период
 // @Tags         subscriptions
 // @Produce      json
-// @Param        start         query  string  true   "Начало периода (MM-YYYY)"
-// @Param        end           query  string  true   "Конец периода (MM-YYYY)"
+// @Param        start         query  string  true   "Начало периода (YYYY-MM-DD или MM-YYYY)"
+// @Param        end           query  string  true   "Конец периода (YYYY-MM-DD или MM-YYYY)"
 // @Param        user_id       query  string  false  "Фильтр по UUID пользователя"
 // @Param        service_name  query  string  false  "Фильтр по названию подписки"
+// @Param        prorate       query  bool    false  "Посуточный расчёт стоимости неполных месяцев"
+// @Param        X-Read-Consistency  header  string  false  "strong — читать из primary, а не из реплики"
 // @Success      200  {object}  models.TotalResponse  "Успеx"
 // @Failure      400  {object}  models.ErrorResponse  "Невалидные параметры"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
 // @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /api/v1/subscriptions/total [get]
 func (h *SubscriptionHandler) GetTotalCost(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
 	var request models.TotalCostRequest
-	err := c.QueryParser(&request)
+	err = c.QueryParser(&request)
 	if err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{
 			Status:  false,
@@ -280,17 +427,23 @@ func (h *SubscriptionHandler) GetTotalCost(c *fiber.Ctx) error {
 		})
 	}
 
-	total, err := h.subscriptionService.GetTotalCost(&request)
+	total, err := h.subscriptionService.GetTotalCost(c.UserContext(), scope, &request)
 	if err != nil {
-		log.Printf("[ERROR TOTAL] Error=%v", err)
+		if errors.Is(err, models.ErrForbidden) {
+			return c.Status(403).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "access to other users' subscriptions is forbidden",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to get total cost", "error", err)
 		return c.Status(500).JSON(models.ErrorResponse{
 			Status:  false,
 			Message: "failed to get total cost: " + err.Error(),
 		})
 	}
 
-	log.Printf("[TOTAL] Period=%s to %s Total=%d",
-		request.PeriodStart, request.PeriodEnd, total.Total)
+	slog.DebugContext(c.UserContext(), "total cost calculated",
+		"period_start", request.PeriodStart, "period_end", request.PeriodEnd, "prorate", request.Prorate, "total", total.Total)
 
 	return c.JSON(models.TotalResponse{
 		Status:  true,
@@ -298,3 +451,172 @@ func (h *SubscriptionHandler) GetTotalCost(c *fiber.Ctx) error {
 		Data:    total,
 	})
 }
+
+// ForecastCost возвращает прогноз расходов на ближайшие месяцы
+// @Summary      Прогноз расходов
+// @Description  Учитываются только активные подписки, их end_date и пробные периоды. Период начинается со следующего месяца.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        months        query  int     false  "Количество месяцев (1-36)"  default(1)
+// @Param        user_id       query  string  false  "Фильтр по UUID пользователя"
+// @Param        service_name  query  string  false  "Фильтр по названию подписки"
+// @Param        X-Read-Consistency  header  string  false  "strong — читать из primary, а не из реплики"
+// @Success      200  {object}  models.ForecastResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидные параметры"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/subscriptions/forecast [get]
+func (h *SubscriptionHandler) ForecastCost(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	var request models.ForecastRequest
+	err = c.QueryParser(&request)
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if err := request.Validate(); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	forecast, err := h.subscriptionService.ForecastCost(c.UserContext(), scope, &request)
+	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			return c.Status(403).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "access to other users' subscriptions is forbidden",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to get forecast", "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to get forecast: " + err.Error(),
+		})
+	}
+
+	slog.DebugContext(c.UserContext(), "forecast calculated",
+		"period_start", forecast.PeriodStart, "period_end", forecast.PeriodEnd, "total", forecast.Total)
+
+	return c.JSON(models.ForecastResponse{
+		Status:  true,
+		Message: "success",
+		Data:    forecast,
+	})
+}
+
+// PauseSubscription приостанавливает подписку
+// @Summary      Приостановить подписку
+// @Description  Дни паузы не учитываются при расчёте стоимости.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "ID подписки"
+// @Success      200  {object}  models.SubscriptionResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      409  {object}  models.ErrorResponse  "Недопустимый переход статуса"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/subscriptions/{id}/pause [post]
+func (h *SubscriptionHandler) PauseSubscription(c *fiber.Ctx) error {
+	return h.changeStatus(c, "pause", h.subscriptionService.PauseSubscription)
+}
+
+// ResumeSubscription возобновляет приостановленную подписку
+// @Summary      Возобновить подписку
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "ID подписки"
+// @Success      200  {object}  models.SubscriptionResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      409  {object}  models.ErrorResponse  "Недопустимый переход статуса"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/subscriptions/{id}/resume [post]
+func (h *SubscriptionHandler) ResumeSubscription(c *fiber.Ctx) error {
+	return h.changeStatus(c, "resume", h.subscriptionService.ResumeSubscription)
+}
+
+// CancelSubscription отменяет подписку
+// @Summary      Отменить подписку
+// @Description  Если end_date не задана или позже сегодняшнего дня, она переносится на сегодня.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "ID подписки"
+// @Success      200  {object}  models.SubscriptionResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      404  {object}  models.ErrorResponse  "Подписка не найдена"
+// @Failure      409  {object}  models.ErrorResponse  "Недопустимый переход статуса"
+// @Failure      403  {object}  models.ErrorResponse  "Доступ к подпискам другого пользователя запрещён"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/subscriptions/{id}/cancel [post]
+func (h *SubscriptionHandler) CancelSubscription(c *fiber.Ctx) error {
+	return h.changeStatus(c, "cancel", h.subscriptionService.CancelSubscription)
+}
+
+func (h *SubscriptionHandler) changeStatus(c *fiber.Ctx, action string, change func(ctx context.Context, scope models.Scope, id int) (models.Subscription, error)) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	data, err := change(c.UserContext(), scope, id)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			return c.Status(404).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "subscription not found",
+			})
+		}
+		if errors.Is(err, models.ErrInvalidStatusTransition) {
+			return c.Status(409).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: err.Error(),
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to change subscription status", "action", action, "id", id, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to change subscription status: " + err.Error(),
+		})
+	}
+
+	slog.InfoContext(c.UserContext(), "subscription status changed", "action", action, "id", id, "status", data.Status)
+
+	return c.JSON(models.SubscriptionResponse{
+		Status:  true,
+		Message: "success",
+		Data:    data,
+	})
+}
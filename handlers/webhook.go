@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"log/slog"
+	"test/auth"
+	"test/models"
+	"test/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// CreateWebhook регистрирует вебхук
+// @Summary      Зарегистрировать вебхук
+// @Description  Если secret не передан, он генерируется. Секрет возвращается только в этом ответе.
+// @Description  Каждый запрос подписывается заголовком X-Webhook-Signature: t=<unix>,v1=<hex HMAC-SHA256(secret, "<unix>.<body>")>.
+// @Description  Пустой список events означает подписку на все события.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        body  body  models.CreateWebhookRequest  true  "Тело запроса"
+// @Success      200  {object}  models.WebhookResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный запрос"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/webhooks/ [post]
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	var request models.CreateWebhookRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if err := request.Validate(); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.UserContext(), scope.OrganizationID, request)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to create webhook", "url", request.URL, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to create webhook: " + err.Error(),
+		})
+	}
+
+	slog.InfoContext(c.UserContext(), "webhook created", "id", webhook.ID, "url", webhook.URL)
+
+	return c.JSON(models.WebhookResponse{
+		Status:  true,
+		Message: "success",
+		Data:    webhook,
+	})
+}
+
+// ListWebhooks возвращает зарегистрированные вебхуки
+// @Summary      Список вебхуков
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {object}  models.WebhooksResponse  "Успеx"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/webhooks/ [get]
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(c.UserContext(), scope.OrganizationID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to list webhooks", "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to list webhooks: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.WebhooksResponse{
+		Status:  true,
+		Message: "success",
+		Data:    webhooks,
+	})
+}
+
+// DeleteWebhook отключает вебхук
+// @Summary      Удалить вебхук
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path      int  true  "ID вебхука"
+// @Success      200  {object}  models.SuccessResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      404  {object}  models.ErrorResponse  "Вебхук не найден"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.UserContext(), scope.OrganizationID, id); err != nil {
+		if err.Error() == "webhook not found" {
+			return c.Status(404).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "webhook not found",
+			})
+		}
+		slog.ErrorContext(c.UserContext(), "failed to delete webhook", "id", id, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to delete webhook: " + err.Error(),
+		})
+	}
+
+	slog.InfoContext(c.UserContext(), "webhook deleted", "id", id)
+
+	return c.JSON(models.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+// ListDeliveries возвращает журнал доставок вебхука
+// @Summary      Журнал доставок вебхука
+// @Tags         webhooks
+// @Produce      json
+// @Param        id     path   int  true   "ID вебхука"
+// @Param        limit  query  int  false  "Лимит"  default(50)
+// @Success      200  {object}  models.WebhookDeliveriesResponse  "Успеx"
+// @Failure      400  {object}  models.ErrorResponse  "Невалидный ID"
+// @Failure      500  {object}  models.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /api/v1/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	scope, err := auth.ScopeFromCtx(c)
+	if err != nil {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "invalid request: " + err.Error(),
+		})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.UserContext(), scope.OrganizationID, id, limit)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to list webhook deliveries", "id", id, "error", err)
+		return c.Status(500).JSON(models.ErrorResponse{
+			Status:  false,
+			Message: "failed to list deliveries: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.WebhookDeliveriesResponse{
+		Status:  true,
+		Message: "success",
+		Data:    deliveries,
+	})
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor — RequestID и AccessLog для gRPC. ID берётся из метаданных x-request-id
+// и возвращается в заголовке ответа.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	header := strings.ToLower(RequestIDHeader)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(header); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx = WithRequestID(ctx, requestID)
+		grpc.SetHeader(ctx, metadata.Pairs(header, requestID))
+
+		started := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK:
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(ctx, level, "grpc request",
+			"method", info.FullMethod,
+			"code", code.String(),
+			"duration_ms", time.Since(started).Milliseconds(),
+		)
+
+		return resp, err
+	}
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "REDACTED"
+
+type ctxKey struct{}
+
+// WithRequestID сохраняет ID запроса в контексте; логгер добавляет его в каждую запись с этим контекстом.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(ctxKey{}).(string)
+	return requestID
+}
+
+// New создаёт логгер с уровнем level (debug, info, warn, error) в формате json или text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler добавляет request_id, trace_id и span_id из контекста записи.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Поля с секретами вырезаются целиком, идентификаторы пользователей заменяются
+// стабильным хешем, чтобы записи одного пользователя можно было связать, не раскрывая его ID.
+var (
+	secretKeys = map[string]bool{
+		"password":      true,
+		"secret":        true,
+		"token":         true,
+		"authorization": true,
+		"api_key":       true,
+		"key":           true,
+		"email":         true,
+	}
+	userKeys = map[string]bool{
+		"user_id": true,
+		"user":    true,
+		"sub":     true,
+	}
+	urlKeys = map[string]bool{
+		"url": true,
+	}
+	ipKeys = map[string]bool{
+		"ip": true,
+	}
+)
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+
+	switch {
+	case secretKeys[key]:
+		return slog.String(attr.Key, redacted)
+	case userKeys[key]:
+		return slog.String(attr.Key, HashUserID(attr.Value.String()))
+	case urlKeys[key]:
+		return slog.String(attr.Key, sanitizeURL(attr.Value.String()))
+	case ipKeys[key]:
+		return slog.String(attr.Key, maskIP(attr.Value.String()))
+	}
+
+	return attr
+}
+
+// HashUserID возвращает короткий стабильный псевдоним пользователя для логов.
+func HashUserID(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userID))
+	return "u_" + hex.EncodeToString(sum[:6])
+}
+
+// maskIP оставляет только сеть клиента: /24 для IPv4 и /48 для IPv6.
+func maskIP(raw string) string {
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return redacted
+	}
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// sanitizeURL убирает из адреса логин, пароль и query, где часто передают токены.
+func sanitizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Входящий ID принимается, только если он короткий и без управляющих символов,
+// иначе клиент мог бы подделывать записи в логах.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID берёт ID из X-Request-ID или генерирует новый, возвращает его в ответе
+// и кладёт в c.UserContext(), откуда он попадает в логи сервисов и БД.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDHeader, requestID)
+		c.SetUserContext(WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
+
+// AccessLog пишет по одной записи на запрос. Ошибки сервера — уровнем error, клиента — warn.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(c.UserContext(), level, "http request",
+			"method", c.Method(),
+			"route", c.Route().Path,
+			"path", c.Path(),
+			"status", status,
+			"duration_ms", time.Since(started).Milliseconds(),
+			"ip", c.IP(),
+		)
+
+		return err
+	}
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "subscriptions"
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Длительность HTTP-запросов по маршруту и статусу.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Длительность запросов к БД по методу репозитория.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"method"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Обращения к кешу ответов по типу запроса и результату (hit или miss).",
+	}, []string{"cache", "result"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		dbQueryDuration,
+		cacheRequests,
+	)
+}
+
+// Middleware измеряет длительность запросов. Маршрут берётся шаблоном (/api/v1/subscriptions/:id),
+// чтобы число временных рядов не зависело от ID в пути.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+
+		httpRequestDuration.
+			WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).
+			Observe(time.Since(started).Seconds())
+
+		return err
+	}
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// ObserveQuery записывает длительность запроса метода репозитория. Использование:
+//
+//	defer metrics.ObserveQuery("GetSubscription", time.Now())
+func ObserveQuery(method string, started time.Time) {
+	dbQueryDuration.WithLabelValues(method).Observe(time.Since(started).Seconds())
+}
+
+// ObserveCache учитывает попадание или промах кеша name.
+func ObserveCache(name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(name, result).Inc()
+}
+
+// RegisterDBStats публикует статистику пула соединений (sql.DB.Stats) под меткой db_name.
+func RegisterDBStats(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// SubscriptionCounter реализуется db.DB.
+type SubscriptionCounter interface {
+	CountSubscriptionsByStatus(ctx context.Context) (map[string]int, error)
+}
+
+// RegisterSubscriptionGauges публикует число неудалённых подписок по статусам.
+// Значения считаются запросом к БД при каждом сборе метрик.
+func RegisterSubscriptionGauges(counter SubscriptionCounter) {
+	registry.MustRegister(&subscriptionCollector{counter: counter})
+}
+
+var subscriptionsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "subscriptions"),
+	"Число неудалённых подписок по статусу.",
+	[]string{"status"}, nil,
+)
+
+const collectTimeout = 5 * time.Second
+
+type subscriptionCollector struct {
+	counter SubscriptionCounter
+}
+
+func (c *subscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subscriptionsDesc
+}
+
+func (c *subscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountSubscriptionsByStatus(ctx)
+	if err != nil {
+		slog.Error("failed to collect subscription metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(subscriptionsDesc, err)
+		return
+	}
+
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(subscriptionsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
@@ -0,0 +1,7 @@
+UPDATE subscriptions.subscription
+SET start_date = to_char(to_date(start_date, 'YYYY-MM-DD'), 'MM-YYYY')
+WHERE start_date ~ '^\d{4}-\d{2}-\d{2}$';
+
+UPDATE subscriptions.subscription
+SET end_date = to_char(to_date(end_date, 'YYYY-MM-DD'), 'MM-YYYY')
+WHERE end_date ~ '^\d{4}-\d{2}-\d{2}$';
@@ -0,0 +1,7 @@
+UPDATE subscriptions.subscription
+SET start_date = to_char(to_date(start_date, 'MM-YYYY'), 'YYYY-MM-DD')
+WHERE start_date ~ '^\d{2}-\d{4}$';
+
+UPDATE subscriptions.subscription
+SET end_date = to_char(to_date(end_date, 'MM-YYYY') + INTERVAL '1 month - 1 day', 'YYYY-MM-DD')
+WHERE end_date ~ '^\d{2}-\d{4}$';
@@ -0,0 +1 @@
+ALTER TABLE subscriptions.subscription DROP COLUMN IF EXISTS trial_end_date;
@@ -0,0 +1 @@
+ALTER TABLE subscriptions.subscription ADD COLUMN trial_end_date VARCHAR(10);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS subscriptions.subscription_pause;
+DROP INDEX IF EXISTS subscriptions.idx_subscription_status;
+ALTER TABLE subscriptions.subscription DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+ALTER TABLE subscriptions.subscription
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'paused', 'cancelled', 'expired'));
+
+UPDATE subscriptions.subscription
+SET status = 'expired'
+WHERE end_date IS NOT NULL AND end_date::date < CURRENT_DATE;
+
+CREATE INDEX idx_subscription_status ON subscriptions.subscription(status);
+
+CREATE TABLE subscriptions.subscription_pause (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions.subscription(id),
+    paused_at VARCHAR(10) NOT NULL,
+    resumed_at VARCHAR(10),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_subscription_pause_subscription_id ON subscriptions.subscription_pause(subscription_id);
+CREATE UNIQUE INDEX idx_subscription_pause_open ON subscriptions.subscription_pause(subscription_id) WHERE resumed_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions.subscription_pause
+    DROP CONSTRAINT subscription_pause_subscription_id_fkey,
+    ADD CONSTRAINT subscription_pause_subscription_id_fkey
+        FOREIGN KEY (subscription_id) REFERENCES subscriptions.subscription(id);
+
+DROP TABLE IF EXISTS subscriptions.renewal_reminder;
+DROP TABLE IF EXISTS subscriptions.job_run;
@@ -0,0 +1,21 @@
+CREATE TABLE subscriptions.job_run (
+    name VARCHAR(100) PRIMARY KEY,
+    last_started_at TIMESTAMP,
+    last_finished_at TIMESTAMP,
+    last_error TEXT,
+    last_instance VARCHAR(255),
+    run_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE subscriptions.renewal_reminder (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions.subscription(id) ON DELETE CASCADE,
+    charge_date VARCHAR(10) NOT NULL,
+    sent_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, charge_date)
+);
+
+ALTER TABLE subscriptions.subscription_pause
+    DROP CONSTRAINT subscription_pause_subscription_id_fkey,
+    ADD CONSTRAINT subscription_pause_subscription_id_fkey
+        FOREIGN KEY (subscription_id) REFERENCES subscriptions.subscription(id) ON DELETE CASCADE;
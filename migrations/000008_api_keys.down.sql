@@ -0,0 +1 @@
+DROP TABLE IF EXISTS subscriptions.api_key;
@@ -0,0 +1,9 @@
+DROP POLICY IF EXISTS subscription_org_isolation ON subscriptions.subscription;
+ALTER TABLE subscriptions.subscription DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS subscriptions.idx_webhook_organization;
+DROP INDEX IF EXISTS subscriptions.idx_subscription_organization_user;
+
+ALTER TABLE subscriptions.api_key DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE subscriptions.webhook DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE subscriptions.subscription DROP COLUMN IF EXISTS organization_id;
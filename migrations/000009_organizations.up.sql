@@ -0,0 +1,22 @@
+ALTER TABLE subscriptions.subscription ADD COLUMN organization_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE subscriptions.webhook ADD COLUMN organization_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE subscriptions.api_key ADD COLUMN organization_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_subscription_organization_user ON subscriptions.subscription(organization_id, user_id);
+CREATE INDEX idx_webhook_organization ON subscriptions.webhook(organization_id);
+
+-- RLS включается всегда: при db_rls=true приложение выставляет app.organization_id в каждой транзакции,
+-- фоновые задачи используют '*'. Без настройки политика здесь не пропускает ни одной строки,
+-- начиная с 000012 — пропускает все. Владелец таблицы RLS не ограничивается,
+-- поэтому приложение должно подключаться отдельной ролью без прав владельца.
+ALTER TABLE subscriptions.subscription ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_org_isolation ON subscriptions.subscription
+    USING (
+        current_setting('app.organization_id', true) = '*'
+        OR organization_id = current_setting('app.organization_id', true)
+    )
+    WITH CHECK (
+        current_setting('app.organization_id', true) = '*'
+        OR organization_id = current_setting('app.organization_id', true)
+    );
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS subscriptions.rate_limit_bucket;
@@ -0,0 +1,6 @@
+CREATE UNLOGGED TABLE subscriptions.rate_limit_bucket (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS subscriptions.monthly_spend_state;
+DROP TABLE IF EXISTS subscriptions.monthly_spend;
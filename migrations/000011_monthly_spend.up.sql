@@ -0,0 +1,26 @@
+-- Помесячный агрегат стоимости подписок для GetTotalCost.
+-- Строка — сумма цен подписок группы (организация, пользователь, сервис), оплачиваемых в месяце month,
+-- у которых предыдущий оплачиваемый месяц — prev_month ('-infinity' для первого).
+-- Подписка попадает в итог за месяцы [from, to], если у неё есть оплачиваемый месяц в периоде,
+-- поэтому её цена берётся из строки с month BETWEEN from AND to AND prev_month < from ровно один раз.
+CREATE TABLE subscriptions.monthly_spend (
+    organization_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    month DATE NOT NULL,
+    prev_month DATE NOT NULL,
+    total BIGINT NOT NULL,
+    PRIMARY KEY (organization_id, user_id, service_name, month, prev_month)
+);
+
+CREATE INDEX idx_monthly_spend_organization_month ON subscriptions.monthly_spend(organization_id, month);
+
+-- horizon — последний день последнего материализованного месяца; бессрочные подписки
+-- разворачиваются до него. NULL, пока агрегат ни разу не построен.
+CREATE TABLE subscriptions.monthly_spend_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    horizon DATE,
+    rebuilt_at TIMESTAMPTZ
+);
+
+INSERT INTO subscriptions.monthly_spend_state (id) VALUES (TRUE);
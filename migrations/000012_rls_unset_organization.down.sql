@@ -0,0 +1,11 @@
+DROP POLICY subscription_org_isolation ON subscriptions.subscription;
+
+CREATE POLICY subscription_org_isolation ON subscriptions.subscription
+    USING (
+        current_setting('app.organization_id', true) = '*'
+        OR organization_id = current_setting('app.organization_id', true)
+    )
+    WITH CHECK (
+        current_setting('app.organization_id', true) = '*'
+        OR organization_id = current_setting('app.organization_id', true)
+    );
@@ -0,0 +1,16 @@
+-- Без app.organization_id (db_rls=false) политика пропускает все строки: RLS ограничивает подписки
+-- только там, где приложение выставляет организацию. После транзакции с set_config(..., true)
+-- настройка остаётся в сессии пустой строкой, поэтому пустое значение тоже считается невыставленным.
+-- RLS защищает только subscription: паузы, outbox и monthly_spend читаются по id подписок
+-- или в фоновых задачах, вебхуки и API-ключи фильтруются по организации в запросах.
+DROP POLICY subscription_org_isolation ON subscriptions.subscription;
+
+CREATE POLICY subscription_org_isolation ON subscriptions.subscription
+    USING (
+        COALESCE(current_setting('app.organization_id', true), '') IN ('', '*')
+        OR organization_id = current_setting('app.organization_id', true)
+    )
+    WITH CHECK (
+        COALESCE(current_setting('app.organization_id', true), '') IN ('', '*')
+        OR organization_id = current_setting('app.organization_id', true)
+    );
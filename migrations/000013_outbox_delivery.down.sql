@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS subscriptions.idx_outbox_pending;
+CREATE INDEX idx_outbox_unpublished ON subscriptions.outbox(id) WHERE published_at IS NULL;
+
+ALTER TABLE subscriptions.outbox DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE subscriptions.outbox DROP COLUMN IF EXISTS published_sinks;
@@ -0,0 +1,8 @@
+-- published_sinks — приёмники, уже получившие событие: при повторе оно отправляется только остальным.
+-- failed_at выставляется, когда попытки исчерпаны; такое событие больше не отправляется и не задерживает
+-- следующие события подписки. Чтобы отправить его снова, достаточно сбросить failed_at.
+ALTER TABLE subscriptions.outbox ADD COLUMN published_sinks TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions.outbox ADD COLUMN failed_at TIMESTAMP;
+
+DROP INDEX subscriptions.idx_outbox_unpublished;
+CREATE INDEX idx_outbox_pending ON subscriptions.outbox(aggregate_id, id) WHERE published_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,7 @@
+-- Время ключей хранится с часовым поясом: expires_at приходит из API как момент времени,
+-- а TIMESTAMP сравнивался с NOW() в часовом поясе сессии. Старые значения записаны в UTC.
+ALTER TABLE subscriptions.api_key
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMPTZ USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at AT TIME ZONE 'UTC';
@@ -0,0 +1 @@
+ALTER TABLE subscriptions.outbox DROP COLUMN IF EXISTS next_attempt_at;
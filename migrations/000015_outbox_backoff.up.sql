@@ -0,0 +1,3 @@
+-- next_attempt_at откладывает повтор события после неудачной попытки: задержка растёт экспоненциально,
+-- поэтому недоступность приёмника не исчерпывает попытки за несколько запусков relay.
+ALTER TABLE subscriptions.outbox ADD COLUMN next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var knownScopes = map[string]bool{
+	ScopeRead:  true,
+	ScopeWrite: true,
+	ScopeAdmin: true,
+}
+
+type APIKey struct {
+	ID             int        `db:"id" json:"id"`
+	OrganizationID string     `db:"organization_id" json:"organization_id"`
+	Name           string     `db:"name" json:"name"`
+	Prefix         string     `db:"prefix" json:"prefix" example:"sk_1a2b3c4d"`
+	KeyHash        string     `db:"key_hash" json:"-"`
+	Scopes         StringList `db:"scopes" json:"scopes" swaggertype:"array,string"`
+	UserID         *uuid.UUID `db:"user_id" json:"user_id,omitempty"`
+	ExpiresAt      *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt      *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" example:"billing-batch"`
+	Scopes    []string   `json:"scopes" example:"read,write"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (r *CreateAPIKeyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(r.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+
+	for _, scope := range r.Scopes {
+		if !knownScopes[scope] {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	if r.ExpiresAt != nil && r.ExpiresAt.Before(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+// CreatedAPIKey возвращается один раз при выпуске: в БД хранится только хеш ключа.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"sk_1a2b3c4d_Zm9vYmFyYmF6..."`
+}
+
+type CreatedAPIKeyResponse struct {
+	Status  bool          `json:"status" example:"true"`
+	Message string        `json:"message"`
+	Data    CreatedAPIKey `json:"data"`
+}
+
+type APIKeysResponse struct {
+	Status  bool     `json:"status" example:"true"`
+	Message string   `json:"message"`
+	Data    []APIKey `json:"data"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DateLayout       = "2006-01-02"
+	LegacyDateLayout = "01-2006"
+)
+
+var ErrInvalidDate = errors.New("date must be in format YYYY-MM-DD or MM-YYYY")
+
+// ParseDate разбирает дату в формате YYYY-MM-DD или устаревшем MM-YYYY.
+// Флаг legacy сообщает, что дата была указана с точностью до месяца.
+func ParseDate(value string) (date time.Time, legacy bool, err error) {
+	if date, err := time.Parse(DateLayout, value); err == nil {
+		return date, false, nil
+	}
+
+	if date, err := time.Parse(LegacyDateLayout, value); err == nil {
+		return date, true, nil
+	}
+
+	return time.Time{}, false, ErrInvalidDate
+}
+
+// NormalizeStartDate приводит дату начала к YYYY-MM-DD.
+// Для MM-YYYY берётся первый день месяца.
+func NormalizeStartDate(value string) (string, error) {
+	date, _, err := ParseDate(value)
+	if err != nil {
+		return "", err
+	}
+
+	return date.Format(DateLayout), nil
+}
+
+// NormalizeEndDate приводит дату окончания к YYYY-MM-DD.
+// Для MM-YYYY берётся последний день месяца, чтобы месяц считался оплаченным целиком.
+func NormalizeEndDate(value string) (string, error) {
+	date, legacy, err := ParseDate(value)
+	if err != nil {
+		return "", err
+	}
+
+	if legacy {
+		date = date.AddDate(0, 1, -1)
+	}
+
+	return date.Format(DateLayout), nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value      string
+		want       string
+		wantLegacy bool
+		wantErr    bool
+	}{
+		{value: "2025-03-15", want: "2025-03-15"},
+		{value: "2024-02-29", want: "2024-02-29"},
+		{value: "03-2025", want: "2025-03-01", wantLegacy: true},
+		{value: "12-2024", want: "2024-12-01", wantLegacy: true},
+		{value: "2025-02-29", wantErr: true},
+		{value: "13-2025", wantErr: true},
+		{value: "2025-3-15", wantErr: true},
+		{value: "15.03.2025", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			date, legacy, err := ParseDate(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDate) {
+					t.Fatalf("ParseDate(%q) error = %v, want ErrInvalidDate", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDate(%q) error = %v", tt.value, err)
+			}
+			if got := date.Format(DateLayout); got != tt.want {
+				t.Errorf("ParseDate(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+			if legacy != tt.wantLegacy {
+				t.Errorf("ParseDate(%q) legacy = %v, want %v", tt.value, legacy, tt.wantLegacy)
+			}
+		})
+	}
+}
+
+func TestNormalizeDates(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{value: "2025-03-15", wantStart: "2025-03-15", wantEnd: "2025-03-15"},
+		{value: "03-2025", wantStart: "2025-03-01", wantEnd: "2025-03-31"},
+		{value: "02-2024", wantStart: "2024-02-01", wantEnd: "2024-02-29"},
+		{value: "02-2025", wantStart: "2025-02-01", wantEnd: "2025-02-28"},
+		{value: "12-2025", wantStart: "2025-12-01", wantEnd: "2025-12-31"},
+		{value: "2025/03/15", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, err := NormalizeStartDate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeStartDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			end, err := NormalizeEndDate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEndDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if start != tt.wantStart {
+				t.Errorf("NormalizeStartDate(%q) = %q, want %q", tt.value, start, tt.wantStart)
+			}
+			if end != tt.wantEnd {
+				t.Errorf("NormalizeEndDate(%q) = %q, want %q", tt.value, end, tt.wantEnd)
+			}
+		})
+	}
+}
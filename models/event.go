@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+	EventSubscriptionExpired = "subscription.expired"
+)
+
+var knownEvents = map[string]bool{
+	EventSubscriptionCreated: true,
+	EventSubscriptionUpdated: true,
+	EventSubscriptionDeleted: true,
+	EventSubscriptionExpired: true,
+}
+
+// Event описывает изменение подписки, о котором нужно уведомить внешние системы.
+// ID совпадает с ID записи outbox и позволяет получателю отбрасывать повторы.
+type Event struct {
+	ID         int64        `json:"id" example:"42"`
+	Type       string       `json:"event" example:"subscription.created"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       Subscription `json:"data"`
+}
+
+func (e Event) Payload() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+type OutboxEvent struct {
+	ID             int64      `db:"id"`
+	EventType      string     `db:"event_type"`
+	AggregateID    int        `db:"aggregate_id"`
+	Payload        string     `db:"payload"`
+	Attempts       int        `db:"attempts"`
+	LastError      *string    `db:"last_error"`
+	PublishedSinks StringList `db:"published_sinks"`
+	CreatedAt      time.Time  `db:"created_at"`
+	PublishedAt    *time.Time `db:"published_at"`
+	FailedAt       *time.Time `db:"failed_at"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+}
+
+func (o OutboxEvent) Event() (Event, error) {
+	event := Event{
+		ID:         o.ID,
+		Type:       o.EventType,
+		OccurredAt: o.CreatedAt.UTC(),
+	}
+
+	err := json.Unmarshal([]byte(o.Payload), &event.Data)
+	return event, err
+}
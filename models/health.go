@@ -0,0 +1,23 @@
+package models
+
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+type HealthCheck struct {
+	Status  string      `json:"status" example:"ok"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+type MigrationStatus struct {
+	Version  uint `db:"version" json:"version" example:"10"`
+	Expected uint `db:"-" json:"expected" example:"10"`
+	Dirty    bool `db:"dirty" json:"dirty"`
+}
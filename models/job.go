@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobRun struct {
+	Name           string     `db:"name" json:"name"`
+	LastStartedAt  *time.Time `db:"last_started_at" json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `db:"last_finished_at" json:"last_finished_at,omitempty"`
+	LastError      *string    `db:"last_error" json:"last_error,omitempty"`
+	LastInstance   *string    `db:"last_instance" json:"last_instance,omitempty"`
+	RunCount       int        `db:"run_count" json:"run_count"`
+}
+
+type JobStatus struct {
+	JobRun
+	Interval string `json:"interval" example:"1h0m0s"`
+	Running  bool   `json:"running"`
+}
+
+type JobsResponse struct {
+	Status  bool        `json:"status" example:"true"`
+	Message string      `json:"message"`
+	Data    []JobStatus `json:"data"`
+}
+
+type RenewalReminder struct {
+	SubscriptionID int       `db:"subscription_id" json:"subscription_id"`
+	OrganizationID string    `db:"organization_id" json:"organization_id"`
+	UserID         uuid.UUID `db:"user_id" json:"user_id"`
+	ServiceName    string    `db:"service_name" json:"service_name"`
+	Price          int       `db:"price" json:"price"`
+	ChargeDate     string    `db:"charge_date" json:"charge_date"`
+}
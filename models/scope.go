@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+const (
+	DefaultOrganization = "default"
+	// SystemOrganization используется фоновыми задачами, работающими со всеми организациями
+	SystemOrganization = "*"
+)
+
+// Scope ограничивает операции подписками одной организации и, если задан UserID, одного пользователя.
+// Пустой UserID означает доступ ко всем пользователям организации (администратор).
+type Scope struct {
+	OrganizationID string
+	UserID         *uuid.UUID
+}
+
+// Restrict проверяет явно запрошенный user_id и подставляет пользователя из scope, если он не задан.
+func (s Scope) Restrict(userID *uuid.UUID) (*uuid.UUID, error) {
+	if s.UserID == nil {
+		return userID, nil
+	}
+
+	if userID != nil && *userID != *s.UserID {
+		return nil, ErrForbidden
+	}
+
+	return s.UserID, nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type SubscriptionStatus string
+
+const (
+	StatusActive    SubscriptionStatus = "active"
+	StatusPaused    SubscriptionStatus = "paused"
+	StatusCancelled SubscriptionStatus = "cancelled"
+	StatusExpired   SubscriptionStatus = "expired"
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// Допустимые переходы между статусами. cancelled и expired конечные.
+var statusTransitions = map[SubscriptionStatus][]SubscriptionStatus{
+	StatusActive: {StatusPaused, StatusCancelled, StatusExpired},
+	StatusPaused: {StatusActive, StatusCancelled, StatusExpired},
+}
+
+func (s SubscriptionStatus) CanTransitionTo(next SubscriptionStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type SubscriptionPause struct {
+	ID             int       `db:"id" json:"id"`
+	SubscriptionID int       `db:"subscription_id" json:"subscription_id"`
+	PausedAt       string    `db:"paused_at" json:"paused_at"`
+	ResumedAt      *string   `db:"resumed_at" json:"resumed_at,omitempty"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
@@ -2,36 +2,50 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Subscription struct {
-	ID          int        `db:"id" json:"id"`
-	ServiceName string     `db:"service_name" json:"service_name"`
-	Price       int        `db:"price" json:"price"`
-	UserID      uuid.UUID  `db:"user_id" json:"user_id"`
-	StartDate   string     `db:"start_date" json:"start_date"`
-	EndDate     *string    `db:"end_date" json:"end_date,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
-	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	ID             int                `db:"id" json:"id"`
+	OrganizationID string             `db:"organization_id" json:"organization_id" example:"default"`
+	ServiceName    string             `db:"service_name" json:"service_name"`
+	Price          int                `db:"price" json:"price"`
+	UserID         uuid.UUID          `db:"user_id" json:"user_id"`
+	StartDate      string             `db:"start_date" json:"start_date"`
+	EndDate        *string            `db:"end_date" json:"end_date,omitempty"`
+	TrialEndDate   *string            `db:"trial_end_date" json:"trial_end_date,omitempty"`
+	Status         SubscriptionStatus `db:"status" json:"status" example:"active"`
+	CreatedAt      time.Time          `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `db:"updated_at" json:"updated_at"`
+	DeletedAt      *time.Time         `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type CreateSubscriptionRequest struct {
-	ServiceName string    `json:"service_name" example:"Netflix"`
-	Price       int       `json:"price" example:"1500"`
-	UserID      uuid.UUID `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	StartDate   string    `json:"start_date" example:"01-2026"`
-	EndDate     *string   `json:"end_date,omitempty" example:"10-2026"`
+	ServiceName  string    `json:"service_name" example:"Netflix"`
+	Price        int       `json:"price" example:"1500"`
+	UserID       uuid.UUID `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	StartDate    string    `json:"start_date" example:"2026-01-15"`
+	EndDate      *string   `json:"end_date,omitempty" example:"2026-10-14"`
+	TrialDays    *int      `json:"trial_days,omitempty" example:"14"`
+	TrialEndDate *string   `json:"trial_end_date,omitempty" example:"2026-01-28"`
 }
 
 type UpdateSubscriptionRequest struct {
-	ServiceName *string `json:"service_name,omitempty" example:"Spotify"`
-	Price       *int    `json:"price,omitempty" example:"500"`
-	StartDate   *string `json:"start_date,omitempty" example:"02-2026"`
-	EndDate     *string `json:"end_date,omitempty" example:"12-2026"`
+	ServiceName  *string `json:"service_name,omitempty" example:"Spotify"`
+	Price        *int    `json:"price,omitempty" example:"500"`
+	StartDate    *string `json:"start_date,omitempty" example:"2026-02-01"`
+	EndDate      *string `json:"end_date,omitempty" example:"2026-12-31"`
+	TrialEndDate *string `json:"trial_end_date,omitempty" example:"2026-02-14"`
+}
+
+type ListSubscriptionsFilter struct {
+	Page              int
+	Limit             int
+	UserID            *uuid.UUID
+	TrialEndingWithin *int
 }
 
 type ListSubscriptionsResponse struct {
@@ -44,12 +58,47 @@ type TotalCostRequest struct {
 	PeriodEnd   string     `query:"end" json:"period_end"`
 	UserID      *uuid.UUID `query:"user_id" json:"user_id,omitempty"`
 	ServiceName *string    `query:"service_name" json:"service_name,omitempty"`
+	Prorate     bool       `query:"prorate" json:"prorate,omitempty"`
+}
+
+type ForecastRequest struct {
+	// Months — nil, если параметр не передан; Validate подставляет значение по умолчанию
+	Months      *int       `query:"months" json:"months"`
+	UserID      *uuid.UUID `query:"user_id" json:"user_id,omitempty"`
+	ServiceName *string    `query:"service_name" json:"service_name,omitempty"`
+}
+
+type ForecastMonth struct {
+	Month string `db:"month" json:"month" example:"2026-11"`
+	Total int    `db:"total" json:"total" example:"1500"`
+}
+
+type ForecastCostResponse struct {
+	PeriodStart string          `json:"period_start" example:"2026-11-01"`
+	PeriodEnd   string          `json:"period_end" example:"2027-01-31"`
+	Total       int             `json:"total" example:"4500"`
+	Months      []ForecastMonth `json:"months"`
 }
 
 type TotalCostResponse struct {
 	Total int `json:"total"`
 }
 
+// ErrInvalidDates — даты подписки после изменения противоречат друг другу.
+var ErrInvalidDates = errors.New("invalid subscription dates")
+
+// ValidateDates проверяет порядок дат подписки целиком. UpdateSubscriptionRequest.Validate может сравнить
+// только переданные поля, поэтому итоговая строка проверяется в транзакции изменения.
+func (r Subscription) ValidateDates() error {
+	if r.EndDate != nil && *r.EndDate < r.StartDate {
+		return fmt.Errorf("%w: end_date must not be before start_date", ErrInvalidDates)
+	}
+	if r.TrialEndDate != nil && *r.TrialEndDate < r.StartDate {
+		return fmt.Errorf("%w: trial_end_date must not be before start_date", ErrInvalidDates)
+	}
+	return nil
+}
+
 func (r *Subscription) Validate() error {
 	if r.UserID == uuid.Nil {
 		return errors.New("user_id is required")
@@ -67,14 +116,100 @@ func (r *Subscription) Validate() error {
 		return errors.New("start_date is required")
 	}
 
-	if _, err := time.Parse("01-2006", r.StartDate); err != nil {
-		return errors.New("start_date must be in format MM-YYYY")
+	startDate, err := NormalizeStartDate(r.StartDate)
+	if err != nil {
+		return errors.New("start_date must be in format YYYY-MM-DD or MM-YYYY")
+	}
+	r.StartDate = startDate
+
+	if r.EndDate != nil && *r.EndDate == "" {
+		r.EndDate = nil
+	}
+
+	if r.EndDate != nil {
+		endDate, err := NormalizeEndDate(*r.EndDate)
+		if err != nil {
+			return errors.New("end_date must be in format YYYY-MM-DD or MM-YYYY")
+		}
+		r.EndDate = &endDate
+
+		if endDate < startDate {
+			return errors.New("end_date must not be before start_date")
+		}
+	}
+
+	if r.TrialEndDate != nil && *r.TrialEndDate == "" {
+		r.TrialEndDate = nil
+	}
+
+	if r.TrialEndDate != nil {
+		trialEndDate, err := NormalizeEndDate(*r.TrialEndDate)
+		if err != nil {
+			return errors.New("trial_end_date must be in format YYYY-MM-DD or MM-YYYY")
+		}
+		r.TrialEndDate = &trialEndDate
+
+		if trialEndDate < startDate {
+			return errors.New("trial_end_date must not be before start_date")
+		}
+	}
+
+	return nil
+}
+
+// ApplyTrialDays задаёт окончание пробного периода длиной days дней от start_date.
+// Вызывается после Validate, когда start_date уже приведена к YYYY-MM-DD.
+func (r *Subscription) ApplyTrialDays(days int) error {
+	if days <= 0 {
+		return errors.New("trial_days must be greater than 0")
+	}
+
+	startDate, err := time.Parse(DateLayout, r.StartDate)
+	if err != nil {
+		return err
 	}
 
-	if r.EndDate != nil && *r.EndDate != "" {
-		if _, err := time.Parse("01-2006", *r.EndDate); err != nil {
-			return errors.New("end_date must be in format MM-YYYY")
+	trialEndDate := startDate.AddDate(0, 0, days-1).Format(DateLayout)
+	r.TrialEndDate = &trialEndDate
+
+	return nil
+}
+
+func (r *UpdateSubscriptionRequest) Validate() error {
+	if r.ServiceName != nil && *r.ServiceName == "" {
+		return errors.New("service_name must not be empty")
+	}
+
+	if r.Price != nil && *r.Price < 0 {
+		return errors.New("price must be greater than or equal to 0")
+	}
+
+	if r.StartDate != nil {
+		startDate, err := NormalizeStartDate(*r.StartDate)
+		if err != nil {
+			return errors.New("start_date must be in format YYYY-MM-DD or MM-YYYY")
 		}
+		r.StartDate = &startDate
+	}
+
+	if r.EndDate != nil {
+		endDate, err := NormalizeEndDate(*r.EndDate)
+		if err != nil {
+			return errors.New("end_date must be in format YYYY-MM-DD or MM-YYYY")
+		}
+		r.EndDate = &endDate
+	}
+
+	if r.StartDate != nil && r.EndDate != nil && *r.EndDate < *r.StartDate {
+		return errors.New("end_date must not be before start_date")
+	}
+
+	if r.TrialEndDate != nil {
+		trialEndDate, err := NormalizeEndDate(*r.TrialEndDate)
+		if err != nil {
+			return errors.New("trial_end_date must be in format YYYY-MM-DD or MM-YYYY")
+		}
+		r.TrialEndDate = &trialEndDate
 	}
 
 	return nil
@@ -89,12 +224,34 @@ func (r *TotalCostRequest) Validate() error {
 		return errors.New("end is required")
 	}
 
-	if _, err := time.Parse("01-2006", r.PeriodStart); err != nil {
-		return errors.New("start must be in format MM-YYYY")
+	periodStart, err := NormalizeStartDate(r.PeriodStart)
+	if err != nil {
+		return errors.New("start must be in format YYYY-MM-DD or MM-YYYY")
+	}
+
+	periodEnd, err := NormalizeEndDate(r.PeriodEnd)
+	if err != nil {
+		return errors.New("end must be in format YYYY-MM-DD or MM-YYYY")
+	}
+
+	if periodEnd < periodStart {
+		return errors.New("end must not be before start")
 	}
 
-	if _, err := time.Parse("01-2006", r.PeriodEnd); err != nil {
-		return errors.New("end must be in format MM-YYYY")
+	r.PeriodStart = periodStart
+	r.PeriodEnd = periodEnd
+
+	return nil
+}
+
+func (r *ForecastRequest) Validate() error {
+	if r.Months == nil {
+		months := 1
+		r.Months = &months
+	}
+
+	if *r.Months < 1 || *r.Months > 36 {
+		return errors.New("months must be between 1 and 36")
 	}
 
 	return nil
@@ -127,3 +284,9 @@ type TotalResponse struct {
 	Message string            `json:"message"`
 	Data    TotalCostResponse `json:"data"`
 }
+
+type ForecastResponse struct {
+	Status  bool                 `json:"status" example:"true"`
+	Message string               `json:"message"`
+	Data    ForecastCostResponse `json:"data"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscriptionValidateDates(t *testing.T) {
+	date := func(value string) *string { return &value }
+
+	tests := []struct {
+		name         string
+		subscription Subscription
+		wantErr      bool
+	}{
+		{name: "open-ended", subscription: Subscription{StartDate: "2025-03-01"}},
+		{name: "end after start", subscription: Subscription{StartDate: "2025-03-01", EndDate: date("2025-03-31")}},
+		{name: "same day", subscription: Subscription{StartDate: "2025-03-01", EndDate: date("2025-03-01"), TrialEndDate: date("2025-03-01")}},
+		{name: "end before start", subscription: Subscription{StartDate: "2025-03-01", EndDate: date("2025-02-28")}, wantErr: true},
+		{name: "trial before start", subscription: Subscription{StartDate: "2025-03-01", TrialEndDate: date("2025-02-01")}, wantErr: true},
+		// Так бывает, если start_date перенесли вперёд, не трогая end_date
+		{name: "start moved past end", subscription: Subscription{StartDate: "2026-01-01", EndDate: date("2025-12-31")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.subscription.ValidateDates()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidDates) {
+				t.Errorf("ValidateDates() error = %v, want ErrInvalidDates", err)
+			}
+		})
+	}
+}
+
+func TestForecastRequestValidate(t *testing.T) {
+	months := func(value int) *int { return &value }
+
+	tests := []struct {
+		name       string
+		months     *int
+		wantMonths int
+		wantErr    bool
+	}{
+		{name: "absent", wantMonths: 1},
+		{name: "explicit", months: months(12), wantMonths: 12},
+		{name: "maximum", months: months(36), wantMonths: 36},
+		{name: "zero", months: months(0), wantErr: true},
+		{name: "negative", months: months(-1), wantErr: true},
+		{name: "too many", months: months(37), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ForecastRequest{Months: tt.months}
+			err := req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *req.Months != tt.wantMonths {
+				t.Errorf("Months = %d, want %d", *req.Months, tt.wantMonths)
+			}
+		})
+	}
+}
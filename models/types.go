@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// StringList хранится в БД как TEXT со значениями через запятую.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+func (l *StringList) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+		raw = ""
+	default:
+		return fmt.Errorf("unsupported type for StringList: %T", src)
+	}
+
+	*l = StringList{}
+	if raw != "" {
+		*l = strings.Split(raw, ",")
+	}
+	return nil
+}
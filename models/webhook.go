@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID             int        `db:"id" json:"id"`
+	OrganizationID string     `db:"organization_id" json:"organization_id"`
+	URL            string     `db:"url" json:"url"`
+	Secret         string     `db:"secret" json:"secret,omitempty"`
+	Events         StringList `db:"events" json:"events" swaggertype:"array,string"` // пустой список — все события
+	Active         bool       `db:"active" json:"active"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeletedAt      *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" example:"https://billing.example.com/hooks/subscriptions"`
+	Secret string   `json:"secret,omitempty" example:"s3cr3t"`
+	Events []string `json:"events,omitempty" example:"subscription.created,subscription.deleted"`
+}
+
+func (r *CreateWebhookRequest) Validate() error {
+	parsed, err := url.Parse(r.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	for _, event := range r.Events {
+		if !knownEvents[event] {
+			return fmt.Errorf("unknown event: %s", event)
+		}
+	}
+
+	return nil
+}
+
+type WebhookDelivery struct {
+	ID             int        `db:"id" json:"id"`
+	WebhookID      int        `db:"webhook_id" json:"webhook_id"`
+	Event          string     `db:"event" json:"event"`
+	Payload        string     `db:"payload" json:"payload"`
+	Status         string     `db:"status" json:"status" example:"pending"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	ResponseStatus *int       `db:"response_status" json:"response_status,omitempty"`
+	LastError      *string    `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery — доставка вместе с адресом и секретом вебхука для отправки.
+type PendingDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type WebhookResponse struct {
+	Status  bool    `json:"status" example:"true"`
+	Message string  `json:"message"`
+	Data    Webhook `json:"data"`
+}
+
+type WebhooksResponse struct {
+	Status  bool      `json:"status" example:"true"`
+	Message string    `json:"message"`
+	Data    []Webhook `json:"data"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Status  bool              `json:"status" example:"true"`
+	Message string            `json:"message"`
+	Data    []WebhookDelivery `json:"data"`
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"test/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor — аналог New для gRPC. Группы сопоставляются с полным именем метода,
+// Exempt задаётся так же, например "/grpc.health.v1.Health/*". С ByIP интерцептор ставится до
+// auth.UnaryServerInterceptor, без него — после, чтобы claims были уже в контексте.
+func UnaryServerInterceptor(cfg Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if matchRoute(info.FullMethod, cfg.Exempt) {
+			return handler(ctx, req)
+		}
+
+		group, limit := cfg.match(info.FullMethod)
+		key := group + "|" + grpcClientKey(ctx)
+		if cfg.ByIP {
+			group, limit = "ip", cfg.Default
+			key = "ip|grpc:" + peerIP(ctx)
+		}
+
+		result, err := cfg.Store.Take(ctx, key, limit)
+		if err != nil {
+			slog.ErrorContext(ctx, "rate limit store failed", "group", group, "error", err)
+			return handler(ctx, req)
+		}
+
+		if !result.Allowed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", seconds(result.RetryAfter(limit))))
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func grpcClientKey(ctx context.Context) string {
+	if claims, ok := auth.ClaimsFromContext(ctx); ok && claims != nil {
+		if claims.APIKeyID != 0 {
+			return "key:" + strconv.Itoa(claims.APIKeyID)
+		}
+		if claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+	}
+	return "ip:" + peerIP(ctx)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
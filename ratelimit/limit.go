@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit — token bucket ёмкостью Requests токенов, которая полностью восполняется за Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit разбирает лимит вида "100/1m" (100 запросов в минуту).
+func ParseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", value)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", value)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// PerSecond — скорость восполнения токенов.
+func (l Limit) PerSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result — состояние корзины после попытки взять токен.
+type Result struct {
+	Allowed bool
+	Tokens  float64
+}
+
+// Remaining — сколько запросов ещё можно сделать прямо сейчас.
+func (r Result) Remaining() int {
+	return int(math.Max(0, math.Floor(r.Tokens)))
+}
+
+// RetryAfter — через сколько появится следующий токен.
+func (r Result) RetryAfter(limit Limit) time.Duration {
+	if r.Tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - r.Tokens) / limit.PerSecond() * float64(time.Second))
+}
+
+// Reset — через сколько корзина восполнится полностью.
+func (r Result) Reset(limit Limit) time.Duration {
+	return time.Duration((float64(limit.Requests) - r.Tokens) / limit.PerSecond() * float64(time.Second))
+}
+
+// Store хранит корзины клиентов. Take атомарно восполняет корзину key и берёт из неё токен, если он есть.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "100/1m", want: Limit{Requests: 100, Period: time.Minute}},
+		{value: " 10/1s ", want: Limit{Requests: 10, Period: time.Second}},
+		{value: "5/1h30m", want: Limit{Requests: 5, Period: 90 * time.Minute}},
+		{value: "100", wantErr: true},
+		{value: "100/", wantErr: true},
+		{value: "/1m", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "ten/1m", wantErr: true},
+		{value: "100/0s", wantErr: true},
+		{value: "100/-1m", wantErr: true},
+		{value: "100/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	limit := Limit{Requests: 60, Period: time.Minute}
+
+	tests := []struct {
+		name          string
+		result        Result
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{name: "full", result: Result{Allowed: true, Tokens: 60}, wantRemaining: 60, wantRetry: 0, wantReset: 0},
+		{name: "partial token", result: Result{Allowed: true, Tokens: 10.5}, wantRemaining: 10, wantRetry: 0, wantReset: 49500 * time.Millisecond},
+		{name: "empty", result: Result{Allowed: false, Tokens: 0}, wantRemaining: 0, wantRetry: time.Second, wantReset: time.Minute},
+		{name: "almost a token", result: Result{Allowed: false, Tokens: 0.75}, wantRemaining: 0, wantRetry: 250 * time.Millisecond, wantReset: 59250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Remaining(); got != tt.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", got, tt.wantRemaining)
+			}
+			if got := tt.result.RetryAfter(limit); got != tt.wantRetry {
+				t.Errorf("RetryAfter() = %s, want %s", got, tt.wantRetry)
+			}
+			if got := tt.result.Reset(limit); got != tt.wantReset {
+				t.Errorf("Reset() = %s, want %s", got, tt.wantReset)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Period: time.Minute}
+	store := NewMemoryStore()
+
+	for i := 0; i < limit.Requests; i++ {
+		result, err := store.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d was rejected", i+1)
+		}
+		if result.Remaining() != limit.Requests-i-1 {
+			t.Errorf("request %d: Remaining() = %d", i+1, result.Remaining())
+		}
+	}
+
+	result, _ := store.Take(ctx, "client", limit)
+	if result.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+
+	// Другой клиент получает свою корзину
+	if result, _ := store.Take(ctx, "other", limit); !result.Allowed {
+		t.Error("another client was rejected")
+	}
+
+	// За треть периода восполняется один токен
+	store.buckets["client"].updatedAt = store.buckets["client"].updatedAt.Add(-limit.Period / 3)
+	if result, _ := store.Take(ctx, "client", limit); !result.Allowed {
+		t.Error("request after refill was rejected")
+	}
+	if result, _ := store.Take(ctx, "client", limit); result.Allowed {
+		t.Error("refill added more than one token")
+	}
+
+	// Корзина не восполняется сверх ёмкости
+	store.buckets["client"].updatedAt = store.buckets["client"].updatedAt.Add(-10 * limit.Period)
+	result, _ = store.Take(ctx, "client", limit)
+	if !result.Allowed || result.Remaining() != limit.Requests-1 {
+		t.Errorf("after a long pause Remaining() = %d, want %d", result.Remaining(), limit.Requests-1)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	if _, err := store.Take(context.Background(), "idle", limit); err != nil {
+		t.Fatal(err)
+	}
+	store.buckets["idle"].updatedAt = time.Now().Add(-2 * memoryIdleTTL)
+	store.lastSweep = time.Now().Add(-2 * memorySweepEvery)
+
+	if _, err := store.Take(context.Background(), "active", limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Error("active bucket was swept")
+	}
+}
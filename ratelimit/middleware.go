@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"test/auth"
+	"test/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Group задаёт отдельный лимит для маршрутов с префиксом Prefix.
+type Group struct {
+	Prefix string
+	Limit  Limit
+}
+
+type Config struct {
+	Store   Store
+	Default Limit
+	Groups  []Group
+	// Exempt — маршруты без ограничения, например пробы и /metrics. Шаблон с * на конце совпадает по префиксу.
+	Exempt []string
+	// ByIP включает один лимит Default на IP для всех маршрутов без учёта claims. Такое middleware ставится
+	// до auth.New и ограничивает запросы без валидных учётных данных вместе с проверкой API-ключей в БД.
+	ByIP bool
+}
+
+// New возвращает middleware, которое ограничивает запросы каждого клиента в каждой группе маршрутов.
+// Клиент определяется по API-ключу, затем по claim sub токена, затем по IP.
+// Без ByIP middleware должно стоять после auth.New, чтобы claims были уже в контексте.
+// Если хранилище недоступно, запрос пропускается: лимит не должен ронять API.
+func New(cfg Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if matchRoute(c.Path(), cfg.Exempt) {
+			return c.Next()
+		}
+
+		group, limit := cfg.match(c.Path())
+		key := group + "|" + clientKey(c)
+		if cfg.ByIP {
+			group, limit = "ip", cfg.Default
+			key = "ip|" + c.IP()
+		}
+
+		result, err := cfg.Store.Take(c.UserContext(), key, limit)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "rate limit store failed", "group", group, "error", err)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining()))
+		c.Set("RateLimit-Reset", seconds(result.Reset(limit)))
+		c.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+seconds(limit.Period))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, seconds(result.RetryAfter(limit)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Status:  false,
+				Message: "rate limit exceeded",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// match выбирает группу с самым длинным совпавшим префиксом.
+func (cfg Config) match(path string) (string, Limit) {
+	group, limit := "*", cfg.Default
+	for _, g := range cfg.Groups {
+		if strings.HasPrefix(path, g.Prefix) && (group == "*" || len(g.Prefix) > len(group)) {
+			group, limit = g.Prefix, g.Limit
+		}
+	}
+	return group, limit
+}
+
+func matchRoute(path string, routes []string) bool {
+	for _, route := range routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == route {
+			return true
+		}
+	}
+	return false
+}
+
+func clientKey(c *fiber.Ctx) string {
+	if claims, ok := auth.ClaimsFromCtx(c); ok {
+		if claims.APIKeyID != 0 {
+			return "key:" + strconv.Itoa(claims.APIKeyID)
+		}
+		if claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
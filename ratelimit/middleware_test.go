@@ -0,0 +1,261 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"test/auth"
+	"test/models"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeAPIKeys map[string]models.APIKey
+
+func (f fakeAPIKeys) LookupAPIKey(ctx context.Context, rawKey string) (models.APIKey, error) {
+	if key, ok := f[rawKey]; ok {
+		return key, nil
+	}
+	return models.APIKey{}, errors.New("api key not found")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store is down")
+}
+
+func newTestApp(handlers ...fiber.Handler) *fiber.App {
+	app := fiber.New()
+	for _, handler := range handlers {
+		app.Use(handler)
+	}
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, path string, headers map[string]string) (int, map[string]string) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	got := make(map[string]string)
+	for _, name := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Policy", fiber.HeaderRetryAfter} {
+		got[name] = resp.Header.Get(name)
+	}
+	return resp.StatusCode, got
+}
+
+func TestMiddlewareGroups(t *testing.T) {
+	app := newTestApp(New(Config{
+		Store:   NewMemoryStore(),
+		Default: Limit{Requests: 2, Period: time.Minute},
+		Groups: []Group{
+			{Prefix: "/api/v1/subscriptions", Limit: Limit{Requests: 1, Period: time.Minute}},
+			{Prefix: "/api/v1/subscriptions/total", Limit: Limit{Requests: 3, Period: time.Minute}},
+		},
+		Exempt: []string{"/healthz", "/metrics*"},
+	}))
+
+	tests := []struct {
+		name string
+		path string
+		// allowed — сколько запросов подряд проходит до 429, -1 — без ограничения
+		allowed int
+	}{
+		{name: "default group", path: "/api/v1/webhooks", allowed: 2},
+		{name: "prefix group", path: "/api/v1/subscriptions/list", allowed: 1},
+		{name: "longest prefix wins", path: "/api/v1/subscriptions/total", allowed: 3},
+		{name: "exempt route", path: "/healthz", allowed: -1},
+		{name: "exempt prefix", path: "/metrics/extra", allowed: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := tt.allowed + 1
+			if tt.allowed < 0 {
+				requests = 10
+			}
+
+			for i := 1; i <= requests; i++ {
+				status, headers := get(t, app, tt.path, nil)
+				wantStatus := 200
+				if tt.allowed >= 0 && i > tt.allowed {
+					wantStatus = 429
+				}
+				if status != wantStatus {
+					t.Fatalf("request %d: status = %d, want %d", i, status, wantStatus)
+				}
+				if wantStatus == 429 && headers[fiber.HeaderRetryAfter] == "" {
+					t.Error("429 without Retry-After")
+				}
+				if tt.allowed < 0 && headers["RateLimit-Limit"] != "" {
+					t.Error("exempt route has rate limit headers")
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	app := newTestApp(New(Config{Store: NewMemoryStore(), Default: Limit{Requests: 5, Period: time.Minute}}))
+
+	_, headers := get(t, app, "/api/v1/subscriptions", nil)
+	want := map[string]string{
+		"RateLimit-Limit":      "5",
+		"RateLimit-Remaining":  "4",
+		"RateLimit-Policy":     "5;w=60",
+		fiber.HeaderRetryAfter: "",
+	}
+	for name, value := range want {
+		if headers[name] != value {
+			t.Errorf("%s = %q, want %q", name, headers[name], value)
+		}
+	}
+}
+
+func TestMiddlewareClients(t *testing.T) {
+	keys := fakeAPIKeys{
+		"first":  {ID: 1, Scopes: models.StringList{models.ScopeAdmin}},
+		"second": {ID: 2, Scopes: models.StringList{models.ScopeAdmin}},
+	}
+	app := newTestApp(
+		auth.New(nil, keys, nil, nil),
+		New(Config{Store: NewMemoryStore(), Default: Limit{Requests: 1, Period: time.Minute}}),
+	)
+
+	first := map[string]string{auth.APIKeyHeader: "first"}
+	second := map[string]string{auth.APIKeyHeader: "second"}
+
+	if status, _ := get(t, app, "/api/v1/subscriptions", first); status != 200 {
+		t.Fatalf("first key: status = %d", status)
+	}
+	if status, _ := get(t, app, "/api/v1/subscriptions", first); status != 429 {
+		t.Errorf("first key over the limit: status = %d, want 429", status)
+	}
+	if status, _ := get(t, app, "/api/v1/subscriptions", second); status != 200 {
+		t.Errorf("second key shares the first key's bucket: status = %d", status)
+	}
+}
+
+func TestMiddlewareByIPBeforeAuth(t *testing.T) {
+	app := newTestApp(
+		New(Config{Store: NewMemoryStore(), Default: Limit{Requests: 2, Period: time.Minute}, ByIP: true, Exempt: []string{"/healthz"}}),
+		auth.New(nil, fakeAPIKeys{}, []string{"/healthz"}, nil),
+	)
+
+	// Запросы с неверным ключом тоже расходуют лимит IP
+	invalid := map[string]string{auth.APIKeyHeader: "invalid"}
+	for i := 1; i <= 2; i++ {
+		if status, _ := get(t, app, "/api/v1/subscriptions", invalid); status != 401 {
+			t.Fatalf("request %d: status = %d, want 401", i, status)
+		}
+	}
+	if status, _ := get(t, app, "/api/v1/subscriptions", invalid); status != 429 {
+		t.Errorf("status = %d, want 429", status)
+	}
+	if status, _ := get(t, app, "/healthz", nil); status != 200 {
+		t.Errorf("probe: status = %d, want 200", status)
+	}
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	app := newTestApp(New(Config{Store: failingStore{}, Default: Limit{Requests: 1, Period: time.Minute}}))
+
+	for i := 0; i < 3; i++ {
+		if status, _ := get(t, app, "/api/v1/subscriptions", nil); status != 200 {
+			t.Fatalf("status = %d, want 200 when the store is down", status)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	ctxFrom := func(ip string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		return grpc.NewContextWithServerTransportStream(ctx, &fakeStream{})
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		method  string
+		allowed int
+	}{
+		{name: "default", cfg: Config{Default: Limit{Requests: 2, Period: time.Minute}}, method: "/svc/Get", allowed: 2},
+		{name: "group by method", cfg: Config{Default: Limit{Requests: 2, Period: time.Minute},
+			Groups: []Group{{Prefix: "/svc/Create", Limit: Limit{Requests: 1, Period: time.Minute}}}}, method: "/svc/Create", allowed: 1},
+		{name: "by ip", cfg: Config{Default: Limit{Requests: 3, Period: time.Minute}, ByIP: true}, method: "/svc/Get", allowed: 3},
+		{name: "exempt", cfg: Config{Default: Limit{Requests: 1, Period: time.Minute}, Exempt: []string{"/grpc.health.v1.Health/*"}},
+			method: "/grpc.health.v1.Health/Check", allowed: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Store = NewMemoryStore()
+			interceptor := UnaryServerInterceptor(tt.cfg)
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+
+			requests := tt.allowed + 1
+			if tt.allowed < 0 {
+				requests = 5
+			}
+			for i := 1; i <= requests; i++ {
+				stream := &fakeStream{}
+				ctx := grpc.NewContextWithServerTransportStream(ctxFrom("10.0.0.1"), stream)
+				_, err := interceptor(ctx, nil, info, handler)
+
+				wantCode := codes.OK
+				if tt.allowed >= 0 && i > tt.allowed {
+					wantCode = codes.ResourceExhausted
+				}
+				if code := status.Code(err); code != wantCode {
+					t.Fatalf("request %d: code = %s, want %s", i, code, wantCode)
+				}
+				if wantCode == codes.ResourceExhausted && len(stream.header.Get("retry-after")) == 0 {
+					t.Error("ResourceExhausted without retry-after")
+				}
+			}
+
+			// Другой IP не делит корзину с первым
+			if _, err := interceptor(ctxFrom("10.0.0.2"), nil, info, handler); err != nil {
+				t.Errorf("another peer: %v", err)
+			}
+		})
+	}
+}
+
+// fakeStream сохраняет заголовки, выставленные через grpc.SetHeader.
+type fakeStream struct {
+	header metadata.MD
+}
+
+func (s *fakeStream) Method() string { return "" }
+
+func (s *fakeStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *fakeStream) SetTrailer(metadata.MD) error { return nil }
@@ -0,0 +1,26 @@
+package ratelimit
+
+import "context"
+
+// BucketTaker реализуется db.DB: корзина хранится в таблице и обновляется одним атомарным запросом.
+type BucketTaker interface {
+	TakeRateLimitToken(ctx context.Context, key string, capacity, perSecond float64) (tokens float64, allowed bool, err error)
+}
+
+// PostgresStore разделяет лимиты между всеми репликами сервиса.
+type PostgresStore struct {
+	db BucketTaker
+}
+
+func NewPostgresStore(db BucketTaker) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tokens, allowed, err := s.db.TakeRateLimitToken(ctx, key, float64(limit.Requests), limit.PerSecond())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Allowed: allowed, Tokens: tokens}, nil
+}
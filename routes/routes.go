@@ -1,21 +1,66 @@
 package routes
 
 import (
+	"test/auth"
+	"test/graphqlapi"
 	"test/handlers"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func Use(app *fiber.App, subscriptionHandler *handlers.SubscriptionHandler) {
-	api := app.Group("/api/v1/subscriptions")
+func Use(app *fiber.App, subscriptionHandler *handlers.SubscriptionHandler, webhookHandler *handlers.WebhookHandler, apiKeyHandler *handlers.APIKeyHandler, adminHandler *handlers.AdminHandler, healthHandler *handlers.HealthHandler, graphqlHandler *graphqlapi.Handler) {
+	//Пробы для оркестратора
+	{
+		app.Get("/healthz", healthHandler.Liveness)
+		app.Get("/readyz", healthHandler.Readiness)
+	}
+
+	api := app.Group("/api/v1/subscriptions", handlers.ReadYourWrites())
 
 	//Подписки
 	{
 		api.Post("/", subscriptionHandler.CreateSubscription)
 		api.Get("/total", subscriptionHandler.GetTotalCost)
+		api.Get("/forecast", subscriptionHandler.ForecastCost)
 		api.Get("/list", subscriptionHandler.ListSubscriptions)
 		api.Get("/:id", subscriptionHandler.GetSubscription)
 		api.Put("/:id", subscriptionHandler.UpdateSubscription)
 		api.Delete("/:id", subscriptionHandler.DeleteSubscription)
+		api.Post("/:id/pause", subscriptionHandler.PauseSubscription)
+		api.Post("/:id/resume", subscriptionHandler.ResumeSubscription)
+		api.Post("/:id/cancel", subscriptionHandler.CancelSubscription)
+	}
+
+	//GraphQL: запросы через GET и POST, мутации только через POST
+	{
+		graphql := app.Group("/graphql", handlers.ReadYourWrites())
+		graphql.Get("/", graphqlHandler.Handle)
+		graphql.Post("/", graphqlHandler.Handle)
+	}
+
+	webhooks := app.Group("/api/v1/webhooks", auth.RequireAdmin())
+
+	//Вебхуки
+	{
+		webhooks.Post("/", webhookHandler.CreateWebhook)
+		webhooks.Get("/", webhookHandler.ListWebhooks)
+		webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+		webhooks.Get("/:id/deliveries", webhookHandler.ListDeliveries)
+	}
+
+	apiKeys := app.Group("/api/v1/api-keys", auth.RequireAdmin())
+
+	//API-ключи
+	{
+		apiKeys.Post("/", apiKeyHandler.CreateAPIKey)
+		apiKeys.Get("/", apiKeyHandler.ListAPIKeys)
+		apiKeys.Delete("/:id", apiKeyHandler.RevokeAPIKey)
+	}
+
+	admin := app.Group("/api/v1/admin", auth.RequireAdmin())
+
+	//Администрирование
+	{
+		admin.Get("/jobs", adminHandler.ListJobs)
 	}
 }
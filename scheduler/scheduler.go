@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"test/db"
+	"test/models"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// leaderLockName — имя advisory lock лидера, общее для всех реплик.
+const leaderLockName = "scheduler"
+
+// Scheduler запускает периодические задачи внутри процесса.
+// При нескольких репликах задачи выполняет только лидер — реплика, взявшая advisory lock,
+// а время последнего запуска хранится в БД и общее для всех реплик.
+type Scheduler struct {
+	db       *db.DB
+	instance string
+	jobs     []Job
+
+	mu        sync.Mutex
+	running   map[string]bool
+	lastCheck map[string]time.Time
+
+	// leaderMu защищает leader: соединение lock используется задачами по очереди
+	leaderMu sync.Mutex
+	leader   *db.LeaderLock
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(db *db.DB) *Scheduler {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+
+	return &Scheduler{
+		db:        db,
+		instance:  instance,
+		running:   make(map[string]bool),
+		lastCheck: make(map[string]time.Time),
+	}
+}
+
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+}
+
+// Stop отменяет контекст задач и ждёт завершения выполняющихся запусков, но не дольше ctx.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler: jobs did not stop in time: %w", ctx.Err())
+	}
+
+	// Lock снимается сразу, чтобы другая реплика не ждала следующей проверки
+	s.leaderMu.Lock()
+	defer s.leaderMu.Unlock()
+	if s.leader != nil {
+		err := s.leader.Unlock(ctx)
+		s.leader = nil
+		if err != nil {
+			return fmt.Errorf("scheduler: failed to release leader lock: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	// Проверяем чаще интервала, чтобы не пропустить запуск, если лидер сменился
+	ticker := time.NewTicker(checkInterval(job))
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		s.lastCheck[job.Name] = time.Now()
+		s.mu.Unlock()
+
+		s.tryRun(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tryRun(ctx context.Context, job Job) {
+	leader, err := s.isLeader(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "job check failed", "job", job.Name, "error", err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	due, err := s.db.JobRunDue(ctx, job.Name, job.Interval)
+	if err != nil {
+		slog.ErrorContext(ctx, "job check failed", "job", job.Name, "error", err)
+		return
+	}
+	if !due {
+		return
+	}
+
+	if err := s.db.StartJobRun(ctx, job.Name, s.instance); err != nil {
+		slog.ErrorContext(ctx, "failed to record job run", "job", job.Name, "error", err)
+		return
+	}
+
+	s.setRunning(job.Name, true)
+	started := time.Now()
+	runErr := job.Run(ctx)
+	s.setRunning(job.Name, false)
+
+	if runErr != nil {
+		slog.ErrorContext(ctx, "job failed", "job", job.Name, "duration_ms", time.Since(started).Milliseconds(), "error", runErr)
+	} else {
+		slog.InfoContext(ctx, "job finished", "job", job.Name, "duration_ms", time.Since(started).Milliseconds())
+	}
+
+	// Результат записывается и тогда, когда запуск прерван остановкой сервиса
+	if err := s.db.FinishJobRun(context.WithoutCancel(ctx), job.Name, runErr); err != nil {
+		slog.ErrorContext(ctx, "failed to record job run", "job", job.Name, "error", err)
+	}
+}
+
+// isLeader берёт lock лидера, если его ещё нет, и проверяет, что взятый lock не потерян вместе с соединением.
+func (s *Scheduler) isLeader(ctx context.Context) (bool, error) {
+	s.leaderMu.Lock()
+	defer s.leaderMu.Unlock()
+
+	if s.leader != nil {
+		err := s.leader.Check(ctx)
+		if err == nil {
+			return true, nil
+		}
+
+		slog.WarnContext(ctx, "scheduler leader lock lost", "error", err)
+		if err := s.leader.Unlock(context.WithoutCancel(ctx)); err != nil {
+			slog.WarnContext(ctx, "failed to release scheduler leader lock", "error", err)
+		}
+		s.leader = nil
+	}
+
+	leader, acquired, err := s.db.TryLockLeader(ctx, leaderLockName)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	slog.InfoContext(ctx, "scheduler leadership acquired", "instance", s.instance)
+	s.leader = leader
+	return true, nil
+}
+
+func checkInterval(job Job) time.Duration {
+	return min(job.Interval, time.Minute)
+}
+
+// Stalled возвращает задачи, цикл которых на этой реплике давно не проверял расписание
+// (например, завис на блокировке в БД). Выполняющиеся сейчас задачи не считаются зависшими.
+func (s *Scheduler) Stalled() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stalled := []string{}
+	for _, job := range s.jobs {
+		if s.running[job.Name] {
+			continue
+		}
+		if last, ok := s.lastCheck[job.Name]; !ok || time.Since(last) > 3*checkInterval(job) {
+			stalled = append(stalled, job.Name)
+		}
+	}
+	return stalled
+}
+
+func (s *Scheduler) setRunning(name string, running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[name] = running
+}
+
+// Status возвращает состояние всех зарегистрированных задач.
+// Running относится только к текущей реплике.
+func (s *Scheduler) Status(ctx context.Context) ([]models.JobStatus, error) {
+	runs, err := s.db.ListJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.JobRun, len(runs))
+	for _, run := range runs {
+		byName[run.Name] = run
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]models.JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		run, ok := byName[job.Name]
+		if !ok {
+			run = models.JobRun{Name: job.Name}
+		}
+
+		statuses = append(statuses, models.JobStatus{
+			JobRun:   run,
+			Interval: job.Interval.String(),
+			Running:  s.running[job.Name],
+		})
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"test/db"
+	"test/models"
+	"time"
+)
+
+// last_used_at обновляется не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyService struct {
+	db *db.DB
+}
+
+func NewAPIKeyService(db *db.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// CreateAPIKey выпускает ключ вида sk_<prefix>_<secret> в организации organizationID.
+// Сам ключ возвращается только здесь.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, organizationID string, req models.CreateAPIKeyRequest) (models.CreatedAPIKey, error) {
+	prefixBytes := make([]byte, 4)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+
+	prefix := "sk_" + hex.EncodeToString(prefixBytes)
+	rawKey := prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	key := models.APIKey{
+		OrganizationID: organizationID,
+		Name:           req.Name,
+		Prefix:         prefix,
+		KeyHash:        hashAPIKey(rawKey),
+		Scopes:         models.StringList(req.Scopes),
+		UserID:         req.UserID,
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	if err := s.db.CreateAPIKey(ctx, &key); err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+
+	return models.CreatedAPIKey{APIKey: key, Key: rawKey}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, organizationID string) ([]models.APIKey, error) {
+	return s.db.ListAPIKeys(ctx, organizationID)
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, organizationID string, id int) error {
+	return s.db.RevokeAPIKey(ctx, organizationID, id)
+}
+
+// LookupAPIKey проверяет ключ из заголовка X-API-Key и отмечает его использование.
+func (s *APIKeyService) LookupAPIKey(ctx context.Context, rawKey string) (models.APIKey, error) {
+	key, err := s.db.GetActiveAPIKeyByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.db.TouchAPIKey(ctx, key.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to update api key last_used_at", "id", key.ID, "error", err)
+		}
+	}
+
+	return key, nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"test/db"
+	"test/metrics"
+	"time"
+)
+
+// Ключи кеша начинаются с организации, чтобы изменение подписок инвалидировало только её записи:
+//
+//	"default":total:{"period_start":"2025-01-01",...}
+func cacheKey(organizationID, name string, params any) string {
+	encoded, _ := json.Marshal(params)
+	return organizationPrefix(organizationID) + name + ":" + string(encoded)
+}
+
+func organizationPrefix(organizationID string) string {
+	return strconv.Quote(organizationID) + ":"
+}
+
+// cached читает ответ name из кеша в target. Запросы с X-Read-Consistency: strong кеш не читают.
+func (s *SubscriptionService) cached(ctx context.Context, name, key string, target any) bool {
+	if db.PrimaryReads(ctx) {
+		return false
+	}
+
+	data, ok := s.cache.Get(ctx, key)
+	if ok && json.Unmarshal(data, target) != nil {
+		ok = false
+	}
+
+	metrics.ObserveCache(name, ok)
+	return ok
+}
+
+// cacheReplicaLag — сколько после инвалидации не кешируются ответы реплики: она могла ещё не получить изменение,
+// и устаревший ответ остался бы в кеше до cache_ttl.
+const cacheReplicaLag = 5 * time.Second
+
+// cacheGenerations считает инвалидации по организациям. Ответ, прочитанный до инвалидации, но сохраняемый
+// после неё, устарел, поэтому store сравнивает поколение до чтения и в момент записи.
+type cacheGenerations struct {
+	mu sync.Mutex
+	// all увеличивается при сбросе всего кеша
+	all           uint64
+	allResetAt    time.Time
+	organizations map[string]cacheGeneration
+}
+
+type cacheGeneration struct {
+	n             uint64
+	invalidatedAt time.Time
+}
+
+// cacheFill — поколение кеша организации на момент начала чтения из БД.
+type cacheFill struct {
+	organizationID string
+	all            uint64
+	generation     uint64
+	fromReplica    func() bool
+}
+
+// beginFill запоминает поколение кеша организации перед чтением из БД. Чтения нужно выполнять
+// с возвращённым ctx, чтобы store знал, отвечала ли реплика.
+func (s *SubscriptionService) beginFill(ctx context.Context, organizationID string) (context.Context, cacheFill) {
+	ctx, fromReplica := db.TrackReplicaReads(ctx)
+
+	s.generations.mu.Lock()
+	defer s.generations.mu.Unlock()
+
+	return ctx, cacheFill{
+		organizationID: organizationID,
+		all:            s.generations.all,
+		generation:     s.generations.organizations[organizationID].n,
+		fromReplica:    fromReplica,
+	}
+}
+
+// store кеширует ответ, если с начала чтения кеш организации не инвалидировали,
+// а ответ реплики — ещё и если с последней инвалидации прошло больше cacheReplicaLag.
+func (s *SubscriptionService) store(ctx context.Context, fill cacheFill, key string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to cache response", "error", err)
+		return
+	}
+
+	s.generations.mu.Lock()
+	defer s.generations.mu.Unlock()
+
+	current := s.generations.organizations[fill.organizationID]
+	if current.n != fill.generation || s.generations.all != fill.all {
+		return
+	}
+	if fill.fromReplica() {
+		invalidatedAt := current.invalidatedAt
+		if s.generations.allResetAt.After(invalidatedAt) {
+			invalidatedAt = s.generations.allResetAt
+		}
+		if time.Since(invalidatedAt) < cacheReplicaLag {
+			return
+		}
+	}
+
+	s.cache.Set(ctx, key, data)
+}
+
+// invalidate сбрасывает закешированные ответы организации после изменения её подписок.
+func (s *SubscriptionService) invalidate(ctx context.Context, organizationID string) {
+	s.generations.mu.Lock()
+	defer s.generations.mu.Unlock()
+
+	if s.generations.organizations == nil {
+		s.generations.organizations = make(map[string]cacheGeneration)
+	}
+	current := s.generations.organizations[organizationID]
+	s.generations.organizations[organizationID] = cacheGeneration{n: current.n + 1, invalidatedAt: time.Now()}
+
+	s.cache.DeletePrefix(ctx, organizationPrefix(organizationID))
+}
+
+// invalidateAll сбрасывает кеш всех организаций.
+func (s *SubscriptionService) invalidateAll(ctx context.Context) {
+	s.generations.mu.Lock()
+	defer s.generations.mu.Unlock()
+
+	s.generations.all++
+	s.generations.allResetAt = time.Now()
+
+	s.cache.DeletePrefix(ctx, "")
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"test/cache"
+	"testing"
+	"time"
+)
+
+func TestCacheStore(t *testing.T) {
+	const organizationID = "acme"
+	key := cacheKey(organizationID, "total", map[string]string{"period_start": "2025-01-01"})
+
+	tests := []struct {
+		name string
+		// between выполняется между началом чтения и сохранением ответа
+		between     func(ctx context.Context, s *SubscriptionService)
+		fromReplica bool
+		// invalidatedAgo — давность предыдущей инвалидации, 0 — её не было
+		invalidatedAgo time.Duration
+		wantCached     bool
+	}{
+		{name: "primary read", wantCached: true},
+		{name: "replica read", fromReplica: true, wantCached: true},
+		{
+			name:       "invalidated during read",
+			between:    func(ctx context.Context, s *SubscriptionService) { s.invalidate(ctx, organizationID) },
+			wantCached: false,
+		},
+		{
+			name:       "whole cache reset during read",
+			between:    func(ctx context.Context, s *SubscriptionService) { s.invalidateAll(ctx) },
+			wantCached: false,
+		},
+		{
+			name:       "other organization invalidated during read",
+			between:    func(ctx context.Context, s *SubscriptionService) { s.invalidate(ctx, "other") },
+			wantCached: true,
+		},
+		{name: "primary read right after invalidation", invalidatedAgo: time.Second, wantCached: true},
+		{name: "replica read right after invalidation", fromReplica: true, invalidatedAgo: time.Second, wantCached: false},
+		{name: "replica read after the lag window", fromReplica: true, invalidatedAgo: 2 * cacheReplicaLag, wantCached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := &SubscriptionService{cache: cache.NewLRU(10, time.Minute)}
+
+			if tt.invalidatedAgo > 0 {
+				s.invalidate(ctx, organizationID)
+				generation := s.generations.organizations[organizationID]
+				generation.invalidatedAt = time.Now().Add(-tt.invalidatedAgo)
+				s.generations.organizations[organizationID] = generation
+			}
+
+			ctx, fill := s.beginFill(ctx, organizationID)
+			fill.fromReplica = func() bool { return tt.fromReplica }
+			if tt.between != nil {
+				tt.between(ctx, s)
+			}
+			s.store(ctx, fill, key, map[string]int{"total": 100})
+
+			if _, ok := s.cache.Get(ctx, key); ok != tt.wantCached {
+				t.Errorf("cached = %v, want %v", ok, tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	s := &SubscriptionService{cache: cache.NewLRU(10, time.Minute)}
+
+	acme := cacheKey("acme", "list", 1)
+	other := cacheKey("other", "list", 1)
+	for _, key := range []string{acme, other} {
+		s.cache.Set(ctx, key, []byte("{}"))
+	}
+
+	s.invalidate(ctx, "acme")
+	if _, ok := s.cache.Get(ctx, acme); ok {
+		t.Error("invalidated organization is still cached")
+	}
+	if _, ok := s.cache.Get(ctx, other); !ok {
+		t.Error("other organization was invalidated")
+	}
+
+	s.invalidateAll(ctx)
+	if _, ok := s.cache.Get(ctx, other); ok {
+		t.Error("invalidateAll kept an entry")
+	}
+}
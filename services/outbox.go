@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"test/db"
+	"test/models"
+	"time"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxMaxAttempts — после стольких неудачных попыток событие помечается failed и больше не отправляется.
+	// С задержками outboxBackoff это около суток недоступности приёмника.
+	outboxMaxAttempts = 30
+	outboxBaseBackoff = 5 * time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+// EventSink получает события из outbox. Доставка at-least-once: при ошибке событие повторяется
+// для приёмников, которые его ещё не получили, поэтому приёмники должны быть идемпотентны по Event.ID.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event models.Event) error
+}
+
+type OutboxRelay struct {
+	db    *db.DB
+	sinks []EventSink
+}
+
+func NewOutboxRelay(db *db.DB, sinks ...EventSink) *OutboxRelay {
+	return &OutboxRelay{db: db, sinks: sinks}
+}
+
+// Relay публикует неотправленные события по порядку. Порядок соблюдается внутри подписки: после ошибки
+// остальные события той же подписки ждут следующего запуска, события других подписок отправляются.
+// Неудачная отправка повторяется с экспоненциальной задержкой; событие, которое не удалось разобрать
+// или отправить за outboxMaxAttempts попыток, помечается failed.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	events, err := r.db.ListUnpublishedEvents(ctx, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := make(map[int]bool)
+	var errs []error
+	for _, outboxEvent := range events {
+		if blocked[outboxEvent.AggregateID] {
+			continue
+		}
+
+		event, err := outboxEvent.Event()
+		publishedSinks := outboxEvent.PublishedSinks
+		var nextAttempt *time.Time
+		if err == nil {
+			publishedSinks, err = r.publish(ctx, event, publishedSinks)
+			if outboxEvent.Attempts+1 < outboxMaxAttempts {
+				next := time.Now().Add(outboxBackoff(outboxEvent.Attempts + 1))
+				nextAttempt = &next
+			}
+		}
+
+		if err != nil {
+			if markErr := r.db.MarkEventFailed(ctx, outboxEvent.ID, publishedSinks, err, nextAttempt); markErr != nil {
+				return published, markErr
+			}
+			if nextAttempt != nil {
+				blocked[outboxEvent.AggregateID] = true
+			}
+			errs = append(errs, fmt.Errorf("event %d: %w", outboxEvent.ID, err))
+			continue
+		}
+
+		if err := r.db.MarkEventPublished(ctx, outboxEvent.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, errors.Join(errs...)
+}
+
+// publish отправляет событие приёмникам, которых нет в done, и возвращает done вместе с успешными.
+func (r *OutboxRelay) publish(ctx context.Context, event models.Event, done models.StringList) (models.StringList, error) {
+	published := append(models.StringList{}, done...)
+	var errs []error
+	for _, sink := range r.sinks {
+		if slices.Contains(done, sink.Name()) {
+			continue
+		}
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", sink.Name(), err))
+			continue
+		}
+		published = append(published, sink.Name())
+	}
+	return published, errors.Join(errs...)
+}
+
+func outboxBackoff(attempt int) time.Duration {
+	backoff := outboxBaseBackoff << (attempt - 1)
+	if backoff > outboxMaxBackoff || backoff <= 0 {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
+
+// Close освобождает ресурсы приёмников, которым это нужно (например, файлы).
+func (r *OutboxRelay) Close() error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *OutboxRelay) PurgePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return r.db.PurgePublishedEvents(ctx, olderThan)
+}
+
+// WriterSink пишет события построчно в JSON. Используется для stdout и файла.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewStdoutSink() *WriterSink {
+	return &WriterSink{name: "stdout", w: os.Stdout}
+}
+
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+
+	return &WriterSink{name: "file", w: file}, nil
+}
+
+// Close закрывает файл приёмника. stdout не закрывается.
+func (s *WriterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if file, ok := s.w.(*os.File); ok && file != os.Stdout {
+		return file.Close()
+	}
+	return nil
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event models.Event) error {
+	payload, err := event.Payload()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(payload, '\n'))
+	return err
+}
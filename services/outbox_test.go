@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{5, 80 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempt); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// Событие помечается failed не раньше, чем приёмник недоступен несколько часов, а не пару минут
+	var window time.Duration
+	for attempt := 1; attempt < outboxMaxAttempts; attempt++ {
+		window += outboxBackoff(attempt)
+	}
+	if window < 12*time.Hour {
+		t.Errorf("retry window = %v, want at least 12h", window)
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"test/db"
+	"test/models"
+)
+
+type RenewalNotifier interface {
+	NotifyRenewal(ctx context.Context, reminder models.RenewalReminder) error
+}
+
+// LogNotifier пишет напоминания в лог. Используется, пока нет внешнего канала доставки.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyRenewal(ctx context.Context, reminder models.RenewalReminder) error {
+	slog.InfoContext(ctx, "renewal reminder",
+		"subscription_id", reminder.SubscriptionID, "user_id", reminder.UserID, "service", reminder.ServiceName,
+		"price", reminder.Price, "charge_date", reminder.ChargeDate)
+	return nil
+}
+
+type ReminderService struct {
+	db       *db.DB
+	notifier RenewalNotifier
+}
+
+func NewReminderService(db *db.DB, notifier RenewalNotifier) *ReminderService {
+	return &ReminderService{db: db, notifier: notifier}
+}
+
+// SendRenewalReminders отправляет по одному напоминанию на каждое списание в ближайшие within дней.
+func (s *ReminderService) SendRenewalReminders(ctx context.Context, within int) (int, error) {
+	reminders, err := s.db.ListDueRenewals(ctx, within)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reminder := range reminders {
+		if err := s.notifier.NotifyRenewal(ctx, reminder); err != nil {
+			return sent, err
+		}
+
+		if err := s.db.MarkRenewalReminded(ctx, reminder); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
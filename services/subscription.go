@@ -1,68 +1,317 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"test/cache"
 	"test/db"
 	"test/models"
+	"test/tracing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type SubscriptionService struct {
 	db *db.DB
+	// cache хранит ответы GetTotalCost и ListSubscriptions до изменения подписок организации
+	cache       cache.Cache
+	generations cacheGenerations
 }
 
-func NewSubscriptionService(db *db.DB) *SubscriptionService {
-	return &SubscriptionService{db: db}
+func NewSubscriptionService(db *db.DB, cache cache.Cache) *SubscriptionService {
+	return &SubscriptionService{db: db, cache: cache}
 }
 
-func (s *SubscriptionService) CreateSubscription(subscription *models.Subscription) error {
-	err := s.db.CreateSubscription(subscription)
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, scope models.Scope, subscription *models.Subscription) error {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.CreateSubscription")
+	defer span.End()
+
+	if scope.UserID != nil && subscription.UserID != *scope.UserID {
+		return models.ErrForbidden
+	}
+	subscription.OrganizationID = scope.OrganizationID
+
+	err := s.db.CreateSubscription(ctx, subscription)
 	if err != nil {
 		return err
 	}
+	s.invalidate(ctx, scope.OrganizationID)
 	return nil
 }
 
-func (s *SubscriptionService) GetSubscription(id int) (models.Subscription, error) {
-	data, err := s.db.GetSubscription(id)
+func (s *SubscriptionService) GetSubscription(ctx context.Context, scope models.Scope, id int) (models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.GetSubscription")
+	defer span.End()
+
+	data, err := s.db.GetSubscription(ctx, id, scope)
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
 
-func (s *SubscriptionService) DeleteSubscription(id int) error {
-	if err := s.db.DeleteSubscription(id); err != nil {
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, scope models.Scope, id int) error {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.DeleteSubscription")
+	defer span.End()
+
+	if _, err := s.db.DeleteSubscription(ctx, id, scope); err != nil {
 		return err
 	}
+	s.invalidate(ctx, scope.OrganizationID)
 
 	return nil
 }
 
-func (s *SubscriptionService) ListSubscriptions(page, limit int) (models.ListSubscriptionsResponse, error) {
-	subscriptions, total, err := s.db.ListSubscriptions(page, limit)
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context, scope models.Scope, filter models.ListSubscriptionsFilter) (models.ListSubscriptionsResponse, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListSubscriptions")
+	defer span.End()
+
+	userID, err := scope.Restrict(filter.UserID)
+	if err != nil {
+		return models.ListSubscriptionsResponse{}, err
+	}
+	filter.UserID = userID
+
+	key := cacheKey(scope.OrganizationID, "list", filter)
+	var response models.ListSubscriptionsResponse
+	if s.cached(ctx, "list", key, &response) {
+		return response, nil
+	}
+
+	ctx, fill := s.beginFill(ctx, scope.OrganizationID)
+	subscriptions, total, err := s.db.ListSubscriptions(ctx, scope, filter)
 	if err != nil {
 		return models.ListSubscriptionsResponse{}, err
 	}
 
-	return models.ListSubscriptionsResponse{
+	response = models.ListSubscriptionsResponse{
 		Subscriptions: subscriptions,
 		Total:         total,
-	}, nil
+	}
+	s.store(ctx, fill, key, response)
+
+	return response, nil
+}
+
+// ListSubscriptionsByUsers возвращает не больше perUser подписок каждого из userIDs одним запросом к БД.
+func (s *SubscriptionService) ListSubscriptionsByUsers(ctx context.Context, scope models.Scope, userIDs []uuid.UUID, perUser int) (map[uuid.UUID][]models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListSubscriptionsByUsers")
+	defer span.End()
+
+	if err := restrictUsers(scope, userIDs); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.db.ListSubscriptionsByUsers(ctx, scope, userIDs, perUser)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uuid.UUID][]models.Subscription, len(userIDs))
+	for _, subscription := range subscriptions {
+		byUser[subscription.UserID] = append(byUser[subscription.UserID], subscription)
+	}
+	return byUser, nil
+}
+
+// ListSubscriptionPauses возвращает историю пауз каждой из подписок subscriptionIDs.
+// Подписки вне scope в результат не попадают.
+func (s *SubscriptionService) ListSubscriptionPauses(ctx context.Context, scope models.Scope, subscriptionIDs []int) (map[int][]models.SubscriptionPause, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListSubscriptionPauses")
+	defer span.End()
+
+	pauses, err := s.db.ListSubscriptionPauses(ctx, scope, subscriptionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	bySubscription := make(map[int][]models.SubscriptionPause, len(subscriptionIDs))
+	for _, pause := range pauses {
+		bySubscription[pause.SubscriptionID] = append(bySubscription[pause.SubscriptionID], pause)
+	}
+	return bySubscription, nil
 }
 
-func (s *SubscriptionService) UpdateSubscription(id int, updateSubscription models.UpdateSubscriptionRequest) (models.Subscription, error) {
-	data, err := s.db.UpdateSubscription(id, &updateSubscription)
+func (s *SubscriptionService) UpdateSubscription(ctx context.Context, scope models.Scope, id int, updateSubscription models.UpdateSubscriptionRequest) (models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.UpdateSubscription")
+	defer span.End()
+
+	data, err := s.db.UpdateSubscription(ctx, id, scope, &updateSubscription)
 	if err != nil {
 		return models.Subscription{}, err
 	}
+	s.invalidate(ctx, scope.OrganizationID)
 
 	return data, nil
 }
 
-func (s *SubscriptionService) GetTotalCost(req *models.TotalCostRequest) (models.TotalCostResponse, error) {
-	total, err := s.db.GetTotalCost(req)
+func (s *SubscriptionService) GetTotalCost(ctx context.Context, scope models.Scope, req *models.TotalCostRequest) (models.TotalCostResponse, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.GetTotalCost")
+	defer span.End()
+
+	userID, err := scope.Restrict(req.UserID)
+	if err != nil {
+		return models.TotalCostResponse{}, err
+	}
+	req.UserID = userID
+
+	// req уже нормализован Validate, поэтому "01-2025" и "2025-01-01" дают один ключ
+	key := cacheKey(scope.OrganizationID, "total", req)
+	var response models.TotalCostResponse
+	if s.cached(ctx, "total", key, &response) {
+		return response, nil
+	}
+
+	ctx, fill := s.beginFill(ctx, scope.OrganizationID)
+	total, err := s.db.GetTotalCost(ctx, scope, req)
 	if err != nil {
 		return models.TotalCostResponse{}, err
 	}
 
-	return models.TotalCostResponse{Total: total}, nil
+	response = models.TotalCostResponse{Total: total}
+	s.store(ctx, fill, key, response)
+
+	return response, nil
+}
+
+// GetTotalCostByUsers считает GetTotalCost отдельно для каждого из userIDs одним запросом к БД.
+// req.UserID не учитывается.
+func (s *SubscriptionService) GetTotalCostByUsers(ctx context.Context, scope models.Scope, req *models.TotalCostRequest, userIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.GetTotalCostByUsers")
+	defer span.End()
+
+	if err := restrictUsers(scope, userIDs); err != nil {
+		return nil, err
+	}
+
+	return s.db.GetTotalCostByUsers(ctx, scope, req, userIDs)
+}
+
+// ForecastCost прогнозирует расходы на months полных месяцев, начиная со следующего.
+func (s *SubscriptionService) ForecastCost(ctx context.Context, scope models.Scope, req *models.ForecastRequest) (models.ForecastCostResponse, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ForecastCost")
+	defer span.End()
+
+	userID, err := scope.Restrict(req.UserID)
+	if err != nil {
+		return models.ForecastCostResponse{}, err
+	}
+	req.UserID = userID
+
+	months := *req.Months
+
+	now := time.Now()
+	periodStart := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, months, -1)
+
+	charged, err := s.db.ForecastCost(ctx, scope, req, periodStart.Format(models.DateLayout), periodEnd.Format(models.DateLayout))
+	if err != nil {
+		return models.ForecastCostResponse{}, err
+	}
+
+	totals := make(map[string]int, len(charged))
+	for _, month := range charged {
+		totals[month.Month] = month.Total
+	}
+
+	response := models.ForecastCostResponse{
+		PeriodStart: periodStart.Format(models.DateLayout),
+		PeriodEnd:   periodEnd.Format(models.DateLayout),
+		Months:      make([]models.ForecastMonth, 0, months),
+	}
+
+	for i := 0; i < months; i++ {
+		month := periodStart.AddDate(0, i, 0).Format("2006-01")
+		response.Months = append(response.Months, models.ForecastMonth{Month: month, Total: totals[month]})
+		response.Total += totals[month]
+	}
+
+	return response, nil
+}
+
+func (s *SubscriptionService) PauseSubscription(ctx context.Context, scope models.Scope, id int) (models.Subscription, error) {
+	return s.changeStatus(ctx, scope, id, models.StatusPaused)
+}
+
+func (s *SubscriptionService) ResumeSubscription(ctx context.Context, scope models.Scope, id int) (models.Subscription, error) {
+	return s.changeStatus(ctx, scope, id, models.StatusActive)
+}
+
+func (s *SubscriptionService) CancelSubscription(ctx context.Context, scope models.Scope, id int) (models.Subscription, error) {
+	return s.changeStatus(ctx, scope, id, models.StatusCancelled)
+}
+
+func (s *SubscriptionService) ExpireSubscriptions(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ExpireSubscriptions")
+	defer span.End()
+
+	expired, err := s.db.ExpireSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	organizations := make(map[string]struct{})
+	for _, subscription := range expired {
+		organizations[subscription.OrganizationID] = struct{}{}
+	}
+	for organizationID := range organizations {
+		s.invalidate(ctx, organizationID)
+	}
+
+	return len(expired), nil
+}
+
+func (s *SubscriptionService) PurgeDeletedSubscriptions(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.PurgeDeletedSubscriptions")
+	defer span.End()
+
+	return s.db.PurgeDeletedSubscriptions(ctx, olderThan)
+}
+
+// RebuildMonthlySpend пересчитывает помесячный агрегат стоимости и сбрасывает кеш,
+// так как перестройка может исправить расхождения агрегата с подписками.
+func (s *SubscriptionService) RebuildMonthlySpend(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.RebuildMonthlySpend")
+	defer span.End()
+
+	rows, err := s.db.RebuildMonthlySpend(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidateAll(ctx)
+
+	return rows, nil
+}
+
+func (s *SubscriptionService) changeStatus(ctx context.Context, scope models.Scope, id int, to models.SubscriptionStatus) (models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.changeStatus")
+	defer span.End()
+
+	current, err := s.db.GetSubscription(ctx, id, scope)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	if !current.Status.CanTransitionTo(to) {
+		return models.Subscription{}, fmt.Errorf("%w: %s -> %s", models.ErrInvalidStatusTransition, current.Status, to)
+	}
+
+	updated, err := s.db.UpdateSubscriptionStatus(ctx, id, scope, current.Status, to)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	s.invalidate(ctx, scope.OrganizationID)
+
+	return updated, nil
+}
+
+// restrictUsers проверяет, что scope разрешает доступ к подпискам каждого из userIDs.
+func restrictUsers(scope models.Scope, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if _, err := scope.Restrict(&userID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"test/db"
+	"test/models"
+	"time"
+)
+
+const (
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	webhookBatchSize    = 50
+	webhookTimeout      = 10 * time.Second
+	webhookSignatureHdr = "X-Webhook-Signature"
+)
+
+type WebhookService struct {
+	db     *db.DB
+	client *http.Client
+}
+
+func NewWebhookService(db *db.DB) *WebhookService {
+	return &WebhookService{
+		db:     db,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, organizationID string, req models.CreateWebhookRequest) (models.Webhook, error) {
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return models.Webhook{}, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := models.Webhook{
+		OrganizationID: organizationID,
+		URL:            req.URL,
+		Secret:         secret,
+		Events:         models.StringList(req.Events),
+	}
+
+	if err := s.db.CreateWebhook(ctx, &webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, organizationID string) ([]models.Webhook, error) {
+	webhooks, err := s.db.ListWebhooks(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Секрет показывается только при создании
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, organizationID string, id int) error {
+	return s.db.DeleteWebhook(ctx, organizationID, id)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, organizationID string, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	return s.db.ListWebhookDeliveries(ctx, organizationID, webhookID, limit)
+}
+
+func (s *WebhookService) Name() string {
+	return "webhook"
+}
+
+// Publish ставит событие в очередь доставки вебхукам организации подписки.
+// Сама отправка выполняется DeliverPending.
+func (s *WebhookService) Publish(ctx context.Context, event models.Event) error {
+	payload, err := event.Payload()
+	if err != nil {
+		return err
+	}
+
+	return s.db.EnqueueWebhookDeliveries(ctx, event.Data.OrganizationID, event.Type, payload)
+}
+
+// DeliverPending отправляет доставки, время попытки которых наступило.
+// Неуспешные повторяются с экспоненциальной задержкой до webhookMaxAttempts попыток.
+func (s *WebhookService) DeliverPending(ctx context.Context) (int, error) {
+	deliveries, err := s.db.ListPendingDeliveries(ctx, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		responseStatus, sendErr := s.send(ctx, delivery)
+
+		if sendErr == nil {
+			if err := s.db.RecordDeliveryAttempt(ctx, delivery.ID, models.DeliverySucceeded, responseStatus, nil, nil); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		message := sendErr.Error()
+		status := models.DeliveryPending
+		var nextAttempt *time.Time
+
+		if delivery.Attempts+1 >= webhookMaxAttempts {
+			status = models.DeliveryFailed
+		} else {
+			next := time.Now().Add(webhookBackoff(delivery.Attempts + 1))
+			nextAttempt = &next
+		}
+
+		if err := s.db.RecordDeliveryAttempt(ctx, delivery.ID, status, responseStatus, &message, nextAttempt); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+func (s *WebhookService) send(ctx context.Context, delivery models.PendingDelivery) (*int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set(webhookSignatureHdr, "t="+timestamp+",v1="+SignWebhookPayload(delivery.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return &resp.StatusCode, nil
+}
+
+// SignWebhookPayload возвращает hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Получатель проверяет подпись тем же способом и отбрасывает запросы со старым timestamp.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempt int) time.Duration {
+	backoff := webhookBaseBackoff << (attempt - 1)
+	if backoff > webhookMaxBackoff || backoff <= 0 {
+		return webhookMaxBackoff
+	}
+	return backoff
+}